@@ -0,0 +1,62 @@
+package conf
+
+import (
+	"sync"
+	"time"
+)
+
+// CachePolicy can be implemented by evaluators whose results can be reused, like evaluators
+// reading files or running commands. Results are cached per evaluator call (like file(path))
+// and dropped on reload. Cached calls are evaluated without a default value, and calls without
+// a value (see CheckedEvaluator) are never cached
+type CachePolicy interface {
+	// CacheTTL returns how long results are reused: a negative duration until the next reload,
+	// zero disables caching
+	CacheTTL() time.Duration
+}
+
+// evalCache memoises evaluator results of a single snapshot
+type evalCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value interface{}
+	// expires is zero for results kept until the next reload
+	expires time.Time
+}
+
+func newEvalCache() *evalCache {
+	return &evalCache{entries: make(map[string]cacheEntry)}
+}
+
+// eval returns the cached result of the evaluator call content or evaluates it
+func (c *evalCache) eval(content string, name string, evaluator EvaluatorFunction, params []string, ttl time.Duration, def interface{}) interface{} {
+	if ttl == 0 {
+		return evaluator.Eval(params, def)
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.entries[content]
+	c.mu.Unlock()
+	if ok && (entry.expires.IsZero() || now.Before(entry.expires)) {
+		return entry.value
+	}
+
+	value, err := evalChecked(name, evaluator, params)
+	if err != nil {
+		return def
+	}
+
+	entry = cacheEntry{value: value}
+	if ttl > 0 {
+		entry.expires = now.Add(ttl)
+	}
+	c.mu.Lock()
+	c.entries[content] = entry
+	c.mu.Unlock()
+
+	return value
+}
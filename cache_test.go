@@ -0,0 +1,160 @@
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingEvaluator returns how many times it was called
+type countingEvaluator struct {
+	calls int64
+}
+
+func (c *countingEvaluator) GetFunctionName() string {
+	return "counter"
+}
+func (c *countingEvaluator) Eval(params []string, def interface{}) interface{} {
+	return float64(atomic.AddInt64(&c.calls, 1))
+}
+
+// cachedCountingEvaluator is a countingEvaluator with a cache policy
+type cachedCountingEvaluator struct {
+	countingEvaluator
+	ttl time.Duration
+}
+
+var _ conf.CachePolicy = (*cachedCountingEvaluator)(nil)
+
+func (c *cachedCountingEvaluator) CacheTTL() time.Duration {
+	return c.ttl
+}
+
+func TestConfig_EvalCache(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), `{
+		a: counter(a)
+		b: counter(b)
+	}`)
+
+	for _, test := range []struct {
+		evaluator conf.EvaluatorFunction
+		cached    bool
+	}{
+		{new(countingEvaluator), false},
+		{&cachedCountingEvaluator{ttl: 0}, false},
+		{&cachedCountingEvaluator{ttl: -1}, true},
+		{&cachedCountingEvaluator{ttl: time.Hour}, true},
+	} {
+		configure, err := conf.Load(conf.WithConfigDirs(configDir), conf.WithEvaluators(test.evaluator))
+		if err != nil {
+			t.Fatal(err)
+		}
+		first := configure.GetInt("app.a", 0)
+		for i := 1; i < 3; i++ {
+			expected := first
+			if !test.cached {
+				expected += i
+			}
+			if read := configure.GetInt("app.a", 0); read != expected {
+				t.Errorf("Expected read %d of %T to be %d, got %d", i, test.evaluator, expected, read)
+			}
+		}
+	}
+
+	evaluator := &cachedCountingEvaluator{ttl: time.Hour}
+	configure, err := conf.Load(conf.WithConfigDirs(configDir), conf.WithEvaluators(evaluator), conf.WithoutKeyIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := configure.GetInt("app.a", 0), configure.GetInt("app.b", 0); a != 1 || b != 2 {
+		t.Errorf("Expected calls with different params to be cached separately, got %d %d", a, b)
+	}
+	if a := configure.GetInt("app.a", 0); a != 1 {
+		t.Errorf("Expected cached value 1, got %d", a)
+	}
+	if err = configure.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if a := configure.GetInt("app.a", 0); a != 3 {
+		t.Errorf("Expected the cache to be dropped on reload, got %d", a)
+	}
+
+	evaluator = &cachedCountingEvaluator{ttl: 10 * time.Millisecond}
+	configure, err = conf.Load(conf.WithConfigDirs(configDir), conf.WithEvaluators(evaluator))
+	if err != nil {
+		t.Fatal(err)
+	}
+	configure.GetInt("app.a", 0)
+	time.Sleep(20 * time.Millisecond)
+	if a := configure.GetInt("app.a", 0); a != 2 {
+		t.Errorf("Expected the cached value to expire, got %d", a)
+	}
+}
+
+// lookupEvaluator returns the value of its param inside values, or def, and records
+// defaults it did not get from a getter
+type lookupEvaluator struct {
+	values  map[string]interface{}
+	ttl     time.Duration
+	foreign int64
+}
+
+func (l *lookupEvaluator) GetFunctionName() string {
+	return "lookup"
+}
+func (l *lookupEvaluator) Eval(params []string, def interface{}) interface{} {
+	if def != nil && def != "fallback" {
+		atomic.AddInt64(&l.foreign, 1)
+	}
+	if value, ok := l.values[params[0]]; ok {
+		return value
+	}
+	return def
+}
+func (l *lookupEvaluator) CacheTTL() time.Duration {
+	return l.ttl
+}
+
+func TestConfig_EvalCacheDefaults(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_cache_defaults")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), `{
+		known: lookup(a)
+		missing: lookup(b)
+	}`)
+
+	for _, ttl := range []time.Duration{-1, time.Hour} {
+		evaluator := &lookupEvaluator{values: map[string]interface{}{"a": "found"}, ttl: ttl}
+		configure, err := conf.Load(conf.WithConfigDirs(configDir), conf.WithEvaluators(evaluator))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 2; i++ {
+			checkString(configure, "app.known", "found", t)
+			if missing := configure.GetString("app.missing", "fallback"); missing != "fallback" {
+				t.Errorf("Expected the default of the getter without a value, got %s", missing)
+			}
+		}
+		if configure.IsSet("app.missing") {
+			t.Error("Expected calls without a value not to be set")
+		}
+		if evaluator.foreign != 0 {
+			t.Errorf("Expected evaluators to get only the defaults of getters, got %d other defaults", evaluator.foreign)
+		}
+	}
+}
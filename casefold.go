@@ -0,0 +1,105 @@
+package conf
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// keyIndex maps every object of a snapshot, by map pointer, to its keys by their lower case form.
+// It is only built for case insensitive configs
+type keyIndex map[uintptr]map[string]string
+
+// buildKeyIndex indexes all objects inside configs, keys of the same object differing
+// only by case are reported as ambiguous
+func buildKeyIndex(configs map[string]interface{}) (keyIndex, ValidationErrors) {
+	index := make(keyIndex)
+	var errs ValidationErrors
+
+	var walk func(key string, value interface{})
+	walk = func(key string, value interface{}) {
+		switch typed := value.(type) {
+		case map[string]interface{}:
+			names := make([]string, 0, len(typed))
+			for name := range typed {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			folded := make(map[string]string, len(typed))
+			for _, name := range names {
+				lower := strings.ToLower(name)
+				if other, ok := folded[lower]; ok {
+					errs = append(errs, &ValidationError{
+						Key:     joinKey(key, name),
+						Message: "ambiguous key, differs only by case from " + joinKey(key, other),
+					})
+					continue
+				}
+				folded[lower] = name
+				walk(joinKey(key, name), typed[name])
+			}
+			index[reflect.ValueOf(typed).Pointer()] = folded
+
+		case []interface{}:
+			for position, item := range typed {
+				walk(indexKey(key, position), item)
+			}
+		}
+	}
+	walk("", configs)
+
+	return index, errs
+}
+
+// find returns the value of name inside object, an exact match is preferred
+// over a case insensitive one
+func (index keyIndex) find(object map[string]interface{}, name string) (string, interface{}, bool) {
+	if value, ok := object[name]; ok || index == nil {
+		return name, value, ok
+	}
+	actual, ok := index[reflect.ValueOf(object).Pointer()][strings.ToLower(name)]
+	if !ok {
+		return name, nil, false
+	}
+	return actual, object[actual], true
+}
+
+// foldSteps replaces the names of steps with the names used in the configs,
+// up to the first wildcard or filter
+func (s *snapshot) foldSteps(steps []pathStep) []pathStep {
+	if s.keyIndex == nil {
+		return steps
+	}
+
+	folded := make([]pathStep, len(steps))
+	copy(folded, steps)
+
+	var value interface{} = s.configs
+	for position, step := range folded {
+		switch step.kind {
+		case stepName:
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return folded
+			}
+			folded[position].name, value, ok = s.keyIndex.find(object, step.name)
+			if !ok {
+				return folded
+			}
+		case stepIndex:
+			array, ok := value.([]interface{})
+			if !ok {
+				return folded
+			}
+			index, ok := arrayIndex(array, step.index)
+			if !ok {
+				return folded
+			}
+			value = array[index]
+		default:
+			return folded
+		}
+	}
+	return folded
+}
@@ -0,0 +1,61 @@
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfig_CaseInsensitiveKeys(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_case")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), `{
+		Server: { Port: 8080, Hosts: [{ Name: "a" }, { Name: "b" }] }
+	}`)
+
+	configure, err := conf.Load(conf.WithConfigDirs(configDir), conf.WithCaseInsensitiveKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if port := configure.GetInt("app.server.port", 0); port != 8080 {
+		t.Errorf("Expected 8080, got %d", port)
+	}
+	if port := configure.GetInt("APP.SERVER.PORT", 0); port != 8080 {
+		t.Errorf("Expected 8080, got %d", port)
+	}
+	checkString(configure, "app.server.hosts[-1].name", "b", t)
+	if names, err := configure.Query("app.server.hosts[*].name"); err != nil || len(names) != 2 {
+		t.Errorf("Expected both names, got %v %v", names, err)
+	}
+	if explanation := configure.Explain("app.server.port"); len(explanation.Layers) != 1 || explanation.Layers[0].Line != 2 {
+		t.Errorf("Unexpected explanation %s", explanation)
+	}
+	if unused := configure.UnusedKeys(); len(unused) != 0 {
+		t.Errorf("Expected keys read with another case to be used, got %v", unused)
+	}
+
+	sensitive, err := conf.New(configDir, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sensitive.IsSet("app.server.port") {
+		t.Error("Expected keys to be case sensitive by default")
+	}
+
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), `{
+		server: { port: 8080 }
+		Server: { port: 9090 }
+	}`)
+	_, err = conf.Load(conf.WithConfigDirs(configDir), conf.WithCaseInsensitiveKeys())
+	if errs, ok := err.(conf.ValidationErrors); !ok || len(errs) != 1 || !strings.Contains(errs[0].Message, "ambiguous") {
+		t.Errorf("Expected an ambiguous key error, got %v", err)
+	}
+}
@@ -5,17 +5,16 @@
 package conf
 
 import (
-	"flag"
 	"fmt"
 	"github.com/hjson/hjson-go"
-	"github.com/joho/godotenv"
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"reflect"
 	"strconv"
 	"strings"
 	"math"
+	"sync"
+	"sync/atomic"
 )
 
 // New creates a new config parser with config files at path configDir.
@@ -24,162 +23,283 @@ import (
 // resulting in a fast access time
 // If there are any Evaluation needed those will be applied when accessing variables
 // All folders inside configDir will recursively scanned for .hjson and .json files and
-// any config will be accessible by its relative path connected with dots
+// any config will be accessible by its relative path connected with dots, a configDir
+// which does not exist results in an empty Config
+// Configs having a JSON Schema document in the SchemaDir of configDir are validated
+// after evaluation, all violations are returned as ValidationErrors
+// The .env file is optional unless mode is EnvRequired, a missing required .env
+// is returned as *EnvNotFoundError and invalid env files as *ParseError
 // An error may happen during reading files like access denied
 // if the error causes
-func New(configDir string, envDir string, evalFunctions []EvaluatorFunction) (config *Config, err error) {
-	config = new(Config)
-	err = nil
+func New(configDir string, envDir string, evalFunctions []EvaluatorFunction, mode ...EnvMode) (config *Config, err error) {
+	opts := []Option{WithConfigDirs(configDir), WithEvaluators(evalFunctions...)}
+	if envDir != "" {
+		opts = append(opts, WithEnvDirs(envDir))
+	}
+	if len(mode) > 0 {
+		opts = append(opts, WithEnvMode(mode[0]))
+	}
 
-	var configFiles []string
+	return Load(opts...)
+}
 
-	configFiles = iterateForConfig(configDir, configFiles)
+// loadSnapshot parses all config files, loads env files and validates the configs against
+// their schemas. A nil snapshot is returned with the error when configs can not be used
+func (c *Config) loadSnapshot(evaluators map[string]EvaluatorFunction) (*snapshot, error) {
+	// env files select the profile, so they are loaded before the configs
+	env, profile, err := loadEnv(c.envDirs, c.envMode, c.processEnv, c.profile)
+	if err != nil {
+		return nil, err
+	}
 
-	config.ConfigsMap = make(map[string]interface{})
-	for _, file := range configFiles {
-		if !strings.HasSuffix(file, ".hjson") && !strings.HasSuffix(file, ".json") {
-			continue
+	configsMap := make(map[string]interface{})
+	files := make(map[string][]fileLayer)
+	schemas := make(map[string]*Schema)
+	for _, configDir := range c.configDirs {
+		dirConfigs, dirFiles, err := loadConfigs(configDir, c.decoders, profile, false)
+		if err != nil {
+			return nil, err
 		}
-
-		content, errF := ioutil.ReadFile(file)
-		if errF != nil {
-			config = nil
-			err = errF
-			return
+		configsMap = mergeConfigs(configsMap, dirConfigs)
+		for source, layers := range dirFiles {
+			files[source] = append(files[source], layers...)
 		}
 
-		var conf map[string]interface{}
-		err = hjson.Unmarshal(content, &conf)
+		dirSchemas, err := loadSchemas(configDir)
 		if err != nil {
-			config = nil
-			return
+			return nil, err
+		}
+		for name, schema := range dirSchemas {
+			schemas[name] = schema
+		}
+	}
+
+	s := &snapshot{
+		base:       configsMap,
+		evaluators: bindEnv(evaluators, env.env),
+		files:      files,
+		schemas:    schemas,
+		env:        env.env,
+		envSources: env.sources,
+		profile:    profile,
+		cache:      newEvalCache(),
+		evalPrefix: c.evalPrefix,
+		// values set at runtime survive reloads
+		overrides: c.load().overrides,
+	}
+	if err = c.prepareSnapshot(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// prepareSnapshot applies the overrides of s over its base configs, evaluates them eagerly
+// if enabled, validates them against the schemas and indexes them
+func (c *Config) prepareSnapshot(s *snapshot) error {
+	s.raw = applyOverrides(s.base, s.overrides)
+	s.configs = s.raw
+	s.keyIndex = nil
+	s.flat = nil
+	s.literals = nil
+	if c.eager {
+		configs, literals, errs := evaluateEager(s, c.lazyEvaluators)
+		if len(errs) > 0 {
+			s.addSourceFiles(errs)
+			return errs
+		}
+		s.configs = configs
+		s.literals = literals
+	}
+	if c.caseInsensitive {
+		index, errs := buildKeyIndex(s.configs)
+		if len(errs) > 0 {
+			s.addSourceFiles(errs)
+			return errs
 		}
+		s.keyIndex = index
+	}
+	if err := validateSchemas(s, s.schemas); err != nil {
+		return err
+	}
+	if !c.noIndex {
+		s.flat = buildFlatIndex(s)
+	}
+
+	return nil
+}
 
-		dirname, filename := filepath.Split(file)
-		extension := filepath.Ext(file)
-		filename = filename[:len(filename)-len(extension)]
-		if strings.Trim(dirname, "/\\") != strings.Trim(configDir, "/\\") {
-			dirname = dirname[len(configDir):]
-			dirname = strings.Replace(strings.Trim(dirname, "/\\"), "/", ".", -1) + "." + filename
-			folders := strings.Split(dirname, ".")
-			lenFolders := len(folders)
-			filename = folders[0]
-			for index := lenFolders - 1; index > 0; index-- {
-				conf = map[string]interface{}{
-					folders[index]: conf,
-				}
+// loadConfigs parses all config files inside configDir having a decoder and merges the files
+// of profile over them. Parse errors are returned as *ParseError, or as ParseErrors of all
+// invalid files when aggregate is true
+func loadConfigs(configDir string, decoders map[string]Decoder, profile string, aggregate bool) (configsMap map[string]interface{}, files map[string][]fileLayer, err error) {
+	var parseErrs ParseErrors
+	var dirOverlays, fileOverlays []fileLayer
+	sources := make(map[string]string)
+
+	configsMap = make(map[string]interface{})
+	files = make(map[string][]fileLayer)
+	apply := func(layer fileLayer) {
+		source := sources[layer.file]
+		folders := strings.Split(source, ".")
+		conf := layer.values
+		for index := len(folders) - 1; index > 0; index-- {
+			conf = map[string]interface{}{
+				folders[index]: conf,
 			}
 		}
 
-		config.ConfigsMap[filename] = conf
+		if existing, ok := configsMap[folders[0]].(map[string]interface{}); ok {
+			conf = mergeConfigs(existing, conf)
+		}
+		configsMap[folders[0]] = conf
+		files[source] = append(files[source], layer)
 	}
 
-	if envDir != "" {
-		err = godotenv.Load(filepath.Join(envDir, ".env"))
-		if err == nil {
-			if flag.Lookup("test.v") != nil {
-				err = godotenv.Overload(filepath.Join(envDir, ".env.test"))
+	for _, file := range iterateForConfig(configDir, nil) {
+		decoder, ok := decoders[filepath.Ext(file)]
+		if !ok {
+			continue
+		}
+		if isSchemaFile(file) {
+			continue
+		}
+
+		source, fileProfile, inDir := configSource(configDir, file, profile)
+		if fileProfile != "" && fileProfile != profile && profile != anyProfile {
+			continue
+		}
+
+		content, errF := ioutil.ReadFile(file)
+		if errF != nil {
+			return nil, nil, errF
+		}
+
+		conf, err := decoder(content)
+		if err != nil {
+			parseErr := newParseError(file, content, err)
+			if !aggregate {
+				return nil, nil, parseErr
 			}
+			parseErrs = append(parseErrs, parseErr)
+			continue
+		}
+
+		sources[file] = source
+		layer := fileLayer{file: file, values: conf}
+		switch {
+		case fileProfile == "":
+			apply(layer)
+		case inDir:
+			dirOverlays = append(dirOverlays, layer)
+		default:
+			fileOverlays = append(fileOverlays, layer)
 		}
 	}
 
-	envEval := new(envEvaluator)
-	config.EvaluatorFunctionsMap = map[string]EvaluatorFunction{
-		envEval.GetFunctionName(): envEval,
+	if len(parseErrs) > 0 {
+		return nil, nil, parseErrs
 	}
-	if evalFunctions != nil {
-		for _, evalFunc := range evalFunctions {
-			config.EvaluatorFunctionsMap[evalFunc.GetFunctionName()] = evalFunc
-		}
+
+	// files of the profile directory are applied before <name>.<profile>.hjson files
+	for _, layer := range append(dirOverlays, fileOverlays...) {
+		apply(layer)
 	}
 
-	return
+	return configsMap, files, nil
+}
+
+func decodeHjson(content []byte) (map[string]interface{}, error) {
+	var conf map[string]interface{}
+	err := hjson.Unmarshal(content, &conf)
+	return conf, err
 }
 
 func iterateForConfig(topPath string, configFiles []string) []string {
 	filepath.Walk(topPath, func(path string, info os.FileInfo, err error) error {
-		if info != nil {
-			if info.IsDir() && path != topPath {
-				if info.Name() == "test" {
-					if flag.Lookup("test.v") == nil {
-						return nil
-					}
-				}
-				iterateForConfig(path, configFiles)
-			} else {
-				configFiles = append(configFiles, path)
-			}
+		if info != nil && !info.IsDir() {
+			configFiles = append(configFiles, path)
 		}
 		return nil
 	})
 	return configFiles
 }
-func get(config *Config, key string, def interface{}) interface{} {
-	keys := strings.Split(key, ".")
-	if len(keys) < 1 {
-		return def
-	}
-
-	return iterateForKey(config, &keys, config.ConfigsMap, def)
+// get returns the value of the dotted key, evaluating string values. Objects and arrays are
+// copied with all strings inside them evaluated, see resolveValue.
+// Keys with wildcards or filters return a []interface{} of all selected values
+func get(s *snapshot, key string, def interface{}) interface{} {
+	return getSteps(s, key, nil, def)
 }
-func iterateForKey(config *Config, keys *[]string, conf map[string]interface{}, def interface{}) interface{} {
-	if len(*keys) < 1 {
-		return def
-	}
 
-	key := (*keys)[0]
-	isArray := false
-	var arrayIndex int
-	var err error
-	if strings.Contains(key, "[") && strings.Contains(key, "]") {
-		indexStart := strings.Index(key, "[")
-		indexEnd := strings.Index(key, "]")
-		keyName := key[:indexStart]
+// getSteps is get for a key which was parsed already, steps are parsed from key when nil
+func getSteps(s *snapshot, key string, steps []pathStep, def interface{}) interface{} {
+	if value, ok := getIndexed(s, key, def); ok {
+		return value
+	}
 
-		isArray = true
-		arrayIndex, err = strconv.Atoi(key[indexStart+1 : indexEnd])
-		if err != nil {
+	if steps == nil {
+		var err error
+		if steps, err = parsePath(key, "."); err != nil {
 			return def
 		}
-
-		key = keyName
+	}
+	if len(steps) == 0 {
+		return def
 	}
 
-	if len(*keys) == 1 {
-		if conf[key] != nil {
-			if isArray {
-				return conf[key].([]interface{})[arrayIndex]
-			}
-
-			if reflect.TypeOf(conf[key]).Kind() == reflect.String {
-				strKey := conf[key].(string)
-				return evalStringValue(config, strKey, def)
-			}
-			return conf[key]
+	if isQuery(steps) {
+		matches := query(s, s.configs, steps, nil, nil)
+		if len(matches) == 0 {
+			return def
+		}
+		values := make([]interface{}, len(matches))
+		for index, match := range matches {
+			values[index] = resolveValue(s, formatPath(match.path, "."), match.value)
 		}
+		return values
+	}
 
+	value, ok := lookupSteps(s.configs, steps, s.keyIndex)
+	if !ok || value == nil {
 		return def
 	}
-
-	if conf[key] != nil {
-		if isArray {
-			newKeys := (*keys)[1:]
-			arr := conf[key].([]interface{})
-			return iterateForKey(config, &newKeys, arr[arrayIndex].(map[string]interface{}), def)
+	if len(s.literals) > 0 {
+		key = s.literalKey(steps)
+	}
+	switch typed := value.(type) {
+	case string:
+		if s.literals[key] {
+			return typed
 		}
-
-		newKeys := (*keys)[1:]
-		return iterateForKey(config, &newKeys, conf[key].(map[string]interface{}), def)
+		return evalStringValue(s, typed, def)
+	case map[string]interface{}, []interface{}:
+		return resolveValue(s, key, typed)
 	}
-
-	return def
+	return value
+}
+func evalStringValue(s *snapshot, content string, def interface{}) interface{} {
+	if methodName, params, ok := parseCall(s, content); ok {
+		evaluator := s.evaluators[methodName]
+		if policy, ok := evaluator.(CachePolicy); ok && s.cache != nil {
+			return s.cache.eval(content, methodName, evaluator, params, policy.CacheTTL(), def)
+		}
+		return evaluator.Eval(params, def)
+	}
+	return unescapeCall(s, content)
 }
-func evalStringValue(config *Config, content string, def interface{}) interface{} {
+
+// parseCall splits content like name(param1, param2) into the evaluator name and its
+// sanitized params, ok is false if content is not a call of a registered evaluator.
+// Names must start with the evaluator prefix of the snapshot, like $env(HOST)
+func parseCall(s *snapshot, content string) (methodName string, params []string, ok bool) {
 	evalStartIndex := strings.Index(content, "(")
 	evalEndIndex := strings.Index(content, ")")
-	if evalStartIndex > 0 && evalEndIndex > 0 {
-		methodName := strings.Trim(content[:evalStartIndex], "\"\t' ")
-		if config.EvaluatorFunctionsMap[methodName] != nil {
+	if evalStartIndex > 0 && evalEndIndex > evalStartIndex {
+		methodName = strings.Trim(content[:evalStartIndex], "\"\t' ")
+		if !strings.HasPrefix(methodName, s.evalPrefix) {
+			return "", nil, false
+		}
+		methodName = methodName[len(s.evalPrefix):]
+		if s.evaluators[methodName] != nil {
 			evalParamsString := content[evalStartIndex+1 : evalEndIndex]
 			evalParams := strings.Split(evalParamsString, ",")
 			var evalParamsSanitized []string
@@ -187,10 +307,24 @@ func evalStringValue(config *Config, content string, def interface{}) interface{
 				evalParamsSanitized = append(evalParamsSanitized, strings.Trim(param, "\"\t' "))
 			}
 
-			return config.EvaluatorFunctionsMap[methodName].Eval(evalParamsSanitized, def)
+			return methodName, evalParamsSanitized, true
 		}
 	}
-	return content
+	return "", nil, false
+}
+
+// unescapeCall removes the backslash escaping a literal value which would be
+// an evaluator call otherwise, so \env(production) reads as env(production)
+func unescapeCall(s *snapshot, content string) string {
+	index := strings.Index(content, "\\")
+	if index < 0 || strings.Trim(content[:index], "\"\t' ") != "" {
+		return content
+	}
+	unescaped := content[:index] + content[index+1:]
+	if _, _, ok := parseCall(s, unescaped); !ok {
+		return content
+	}
+	return unescaped
 }
 
 // EvaluatorFunction lets you create dynamic config values
@@ -213,23 +347,177 @@ type EvaluatorFunction interface {
 // to the ConfigsMap.
 // Its much better and easier to use Getter functions of the struct.
 // But when accessing full config objects are needed they are available with GetMap function or
-// throw the ConfigsMap object
+// throw the ConfigsMap method.
+// Config is safe for concurrent use, reloads swap an immutable snapshot of all
+// configs atomically so readers never see a half updated state
 type Config struct {
-	ConfigsMap            map[string]interface{}
-	EvaluatorFunctionsMap map[string]EvaluatorFunction
+	current atomic.Value // *snapshot
+
+	configDirs []string
+	envDirs    []string
+	decoders   map[string]Decoder
+	// delimiter separates the parts of keys given to the config
+	delimiter       string
+	caseInsensitive bool
+	noIndex         bool
+	// eager evaluates calls on load except the ones of lazyEvaluators
+	eager          bool
+	lazyEvaluators map[string]bool
+	evalPrefix     string
+	frozen         bool
+	usage          *usageTracker
+	envMode        EnvMode
+	// processEnv makes the process environment visible to the env evaluator
+	processEnv bool
+	// profile is the profile selected with SetProfile, APP_ENV is used when empty
+	profile string
+
+	// mu guards the callbacks and the watcher, writeMu serializes
+	// everything that stores a new snapshot
+	mu                  sync.RWMutex
+	writeMu             sync.Mutex
+	callbacks           []changeCallback
+	lastCallbackID      uint64
+	reloadErrorCallback func(err error)
+	watcher             *watcher
+
+	// notifyMu guards the changes waiting for their callbacks, which are
+	// called outside of writeMu so callbacks can write to the config
+	notifyMu       sync.Mutex
+	pendingChanges []pendingChange
+	dispatching    bool
+}
+
+// snapshot holds everything needed to answer reads; it is never
+// modified after being stored in a Config, changes always create a new one
+type snapshot struct {
+	configs map[string]interface{}
+	// base holds the merged configs of all files, raw the base with the overrides
+	// applied, both before eager evaluation
+	base       map[string]interface{}
+	raw        map[string]interface{}
+	overrides  []override
+	evaluators map[string]EvaluatorFunction
+	// files maps dotted config names (like dir.inner.inside) to the layers of their
+	// source files, in the order they are merged
+	files   map[string][]fileLayer
+	schemas map[string]*Schema
+	// env holds the environment variables visible to the env evaluator
+	env map[string]string
+	// envSources maps environment variables to the env file defining them
+	envSources map[string]string
+	// profile is the active profile, empty when none is active
+	profile string
+	// keyIndex is set for case insensitive configs
+	keyIndex keyIndex
+	// flat is the flattened index of all keys, nil when disabled
+	flat map[string]flatNode
+	// cache holds results of evaluators implementing CachePolicy
+	cache *evalCache
+	// evalPrefix starts the names of evaluator calls, like $ in $env(HOST)
+	evalPrefix string
+	// literals holds the canonical keys of eagerly evaluated results, which are read as they
+	// are so results looking like evaluator calls are not evaluated again
+	literals map[string]bool
+}
+
+// sourceFile returns the file defining key, or an empty string if it is unknown
+func (s *snapshot) sourceFile(key string) string {
+	file, _ := s.source(key)
+	return file
+}
+
+func (c *Config) load() *snapshot {
+	s, _ := c.current.Load().(*snapshot)
+	if s == nil {
+		return &snapshot{}
+	}
+	return s
+}
+
+func (c *Config) store(s *snapshot) {
+	c.current.Store(s)
+}
+
+// Snapshot returns a Config pinned to the current configs. Reads from the returned
+// Config are not affected by later reloads, which is useful to read several
+// related values consistently (e.g. for the duration of a request)
+func (c *Config) Snapshot() *Config {
+	pinned := &Config{
+		configDirs: c.configDirs,
+		envDirs:    c.envDirs,
+		delimiter:  c.delimiter,
+		frozen:     true,
+		usage:      c.usage,
+	}
+	pinned.store(c.load())
+	return pinned
+}
+
+// ConfigsMap returns all parsed configs of the current snapshot.
+// The returned map is shared between readers and must not be modified, use Set to change values.
+// It replaces the ConfigsMap field of earlier versions
+func (c *Config) ConfigsMap() map[string]interface{} {
+	return c.load().configs
+}
+
+// EvaluatorFunctionsMap returns registered evaluators of the current snapshot by their function name.
+// The returned map is shared between readers and must not be modified, use RegisterEvaluator and
+// UnregisterEvaluator to change the evaluators. It replaces the EvaluatorFunctionsMap field of earlier versions
+func (c *Config) EvaluatorFunctionsMap() map[string]EvaluatorFunction {
+	return c.load().evaluators
 }
 
 // IsSet returns true if there is value for key, false otherwise
 func (c *Config) IsSet(key string) bool {
-	return get(c, key, nil) != nil
+	return get(c.load(), c.canonicalKey(key), nil) != nil
 }
 
 // Get returns the raw interface{} value of a key
 // You have to convert it to your desired type
 // If you have used a custom EvaluatorFunction to generate the value
-// simply cast the interface{} to your desired type
+// simply cast the interface{} to your desired type.
+// Objects and arrays are returned as copies with all evaluator calls inside them evaluated
 func (c *Config) Get(key string, def interface{}) interface{} {
-	return get(c, key, def)
+	s := c.load()
+	key, steps := c.resolveKey(s, key)
+	c.usage.record(key)
+	return getSteps(s, key, steps, def)
+}
+
+// GetRaw returns the value of a key as written in the config files, without evaluating
+// evaluator calls inside it. Objects and arrays are shared and must not be modified
+func (c *Config) GetRaw(key string, def interface{}) interface{} {
+	key = c.canonicalKey(key)
+	c.usage.record(key)
+	return getRaw(c.load(), key, def)
+}
+
+// getRaw reads key from the configs as written in the files, a snapshot
+// without evaluators returns string values as they are
+func getRaw(s *snapshot, key string, def interface{}) interface{} {
+	steps, err := parsePath(key, ".")
+	if err != nil || len(steps) == 0 {
+		return def
+	}
+
+	if isQuery(steps) {
+		matches := query(&snapshot{configs: s.raw}, s.raw, steps, nil, nil)
+		if len(matches) == 0 {
+			return def
+		}
+		values := make([]interface{}, len(matches))
+		for index, match := range matches {
+			values[index] = match.value
+		}
+		return values
+	}
+
+	value, ok := lookupSteps(s.raw, steps, nil)
+	if !ok || value == nil {
+		return def
+	}
+	return value
 }
 
 // GetString checks if the value of the key can be converted to string or not
@@ -286,9 +574,10 @@ func (c *Config) GetFloat(key string, def float64) float64 {
 // if not or if the key does not exist returns the def value
 // valid values are true,false,1,0
 func (c *Config) GetBoolean(key string, def bool) bool {
-	val, ok := c.Get(key, def).(bool)
+	raw := c.Get(key, def)
+	val, ok := raw.(bool)
 	if !ok {
-		val, ok := c.Get(key, def).(float64)
+		val, ok := raw.(float64)
 		if ok {
 			return val == 1
 		}
@@ -302,15 +591,23 @@ func (c *Config) GetBoolean(key string, def bool) bool {
 // GetStringArray checks if the value of the key can be converted to []string or not
 // if not or if the key does not exist returns the def value
 func (c *Config) GetStringArray(key string, def []string) []string {
-	arr, ok := c.Get(key, def).([]string)
+	key = c.canonicalKey(key)
+	c.usage.record(key)
+	raw := get(c.load(), key, def)
+	arr, ok := raw.([]string)
 	if ok {
 		return arr
 	}
 
-	arrS := c.Get(key, def).([]interface{})
+	arrS, ok := raw.([]interface{})
+	if !ok {
+		return def
+	}
 	var foundStrings = make([]string, len(arrS))
 	for index, item := range arrS {
-		foundStrings[index] = evalStringValue(c, item.(string), item.(string)).(string)
+		if foundStrings[index], ok = item.(string); !ok {
+			return def
+		}
 	}
 	return foundStrings
 }
@@ -318,15 +615,24 @@ func (c *Config) GetStringArray(key string, def []string) []string {
 // GetIntArray checks if the value of the key can be converted to []int or not
 // if not or if the key does not exist returns the def value
 func (c *Config) GetIntArray(key string, def []int) []int {
-	arr, ok := c.Get(key, def).([]int)
+	raw := c.Get(key, def)
+	arr, ok := raw.([]int)
 	if ok {
 		return arr
 	}
 
-	arrI := c.Get(key, def).([]interface{})
+	arrI, ok := raw.([]interface{})
+	if !ok {
+		return def
+	}
 	var foundArray = make([]int, len(arrI))
 	for index, item := range arrI {
-		foundArray[index] = int(item.(float64))
+		// evaluators like env return numbers as strings
+		number, err := toFloat(item)
+		if err != nil {
+			return def
+		}
+		foundArray[index] = int(number)
 	}
 	return foundArray
 }
@@ -334,20 +640,28 @@ func (c *Config) GetIntArray(key string, def []int) []int {
 // GetFloatArray checks if the value of the key can be converted to []float64 or not
 // if not or if the key does not exist returns the def value
 func (c *Config) GetFloatArray(key string, def []float64) []float64 {
-	arr, ok := c.Get(key, def).([]float64)
+	raw := c.Get(key, def)
+	arr, ok := raw.([]float64)
 	if ok {
 		return arr
 	}
 
-	arrF := c.Get(key, def).([]interface{})
+	arrF, ok := raw.([]interface{})
+	if !ok {
+		return def
+	}
 	var foundArray = make([]float64, len(arrF))
 	for index, item := range arrF {
-		foundArray[index] = item.(float64)
+		number, err := toFloat(item)
+		if err != nil {
+			return def
+		}
+		foundArray[index] = number
 	}
 	return foundArray
 }
 
-// GetMap returns the raw config object as map of strings
+// GetMap returns the config object as map of strings with all evaluator calls inside it evaluated
 func (c *Config) GetMap(key string, def map[string]interface{}) map[string]interface{} {
 	mapVal, ok := c.Get(key, def).(map[string]interface{})
 	if ok {
@@ -2,6 +2,7 @@ package conf_test
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -50,7 +51,7 @@ func TestNotFoundFile(t *testing.T) {
 
 	_, err = conf.New(filepath.Join(rootDir, "test_configs"), rootDir, []conf.EvaluatorFunction{
 		new(testEvalFunction),
-	})
+	}, conf.EnvRequired)
 
 	if err == nil {
 		t.Error("File not exist but got no error!")
@@ -78,6 +79,43 @@ func TestInvalidFile(t *testing.T) {
 	t.Log(err)
 }
 
+func TestParseErrors(t *testing.T) {
+	root, err := os.Executable()
+	if err != nil {
+		panic(err)
+	}
+
+	rootDir := filepath.Join(filepath.Dir(root), "..")
+	t.Log("Searching Config files at: " + rootDir)
+
+	_, err = conf.New(filepath.Join(rootDir, "test_configs/invalids"), rootDir, nil)
+	if parseErr, ok := err.(*conf.ParseError); !ok || parseErr.File == "" || parseErr.Line == 0 {
+		t.Errorf("Expected a ParseError with position, got %v", err)
+	}
+
+	err = conf.CheckFiles(filepath.Join(rootDir, "test_configs/invalids"))
+	parseErrs, ok := err.(conf.ParseErrors)
+	if !ok || len(parseErrs) != 2 {
+		t.Fatalf("Expected ParseErrors for both invalid files, got %v", err)
+	}
+	t.Log(err)
+
+	expected := map[string][2]int{
+		"broken.json":   {4, 29},
+		"corrupt.hjson": {3, 1},
+	}
+	for _, parseErr := range parseErrs {
+		position, found := expected[filepath.Base(parseErr.File)]
+		if !found || parseErr.Line != position[0] || parseErr.Column != position[1] || parseErr.Snippet == "" {
+			t.Errorf("Unexpected parse error %+v", parseErr)
+		}
+	}
+
+	if err = conf.CheckFiles(filepath.Join(rootDir, "test_configs/valids")); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestNestedString(t *testing.T) {
 	root, err := os.Executable()
 	if err != nil {
@@ -268,6 +306,129 @@ func TestConfig_GetAndIsSet(t *testing.T) {
 	}
 }
 
+func TestConfig_NestedEvaluation(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_nested")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), `{
+		server: {
+			host: paramsJoin(a, b)
+			ports: [
+				counter()
+				8081
+			]
+			envPorts: [
+				"env(CONF_TEST_UNSET_PORT, 80)"
+				81
+			]
+			nested: {
+				names: [
+					paramsJoin(c, d)
+				]
+			}
+		}
+	}`)
+
+	configure, err := conf.Load(conf.WithConfigDirs(configDir), conf.WithEvaluators(new(testEvalFunction), new(countingEvaluator)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := configure.GetMap("app.server", nil)
+	if server["host"] != "a:b" {
+		t.Errorf("Expected evaluated values inside maps, got %v", server["host"])
+	}
+	names := server["nested"].(map[string]interface{})["names"].([]interface{})
+	if names[0] != "c:d" {
+		t.Errorf("Expected evaluated values inside nested arrays, got %v", names[0])
+	}
+	if ports := configure.GetIntArray("app.server.ports", nil); len(ports) != 2 || ports[0] != 2 || ports[1] != 8081 {
+		t.Errorf("Expected evaluated int array, got %v", ports)
+	}
+	if names, ok := configure.Get("app.server.nested.names[*]", nil).([]interface{}); !ok || names[0] != "c:d" {
+		t.Errorf("Expected evaluated query results, got %v", names)
+	}
+
+	if ports := configure.GetFloatArray("app.server.envPorts", nil); len(ports) != 2 || ports[0] != 80 || ports[1] != 81 {
+		t.Errorf("Expected env elements converted to numbers, got %v", ports)
+	}
+	if ports := configure.GetIntArray("app.server.envPorts", nil); len(ports) != 2 || ports[0] != 80 || ports[1] != 81 {
+		t.Errorf("Expected env elements converted to numbers, got %v", ports)
+	}
+	if names := configure.GetStringArray("app.server.ports", []string{"def"}); len(names) != 1 || names[0] != "def" {
+		t.Errorf("Expected the default for elements which are not strings, got %v", names)
+	}
+	if ports := configure.GetIntArray("app.server.host", []int{1}); len(ports) != 1 || ports[0] != 1 {
+		t.Errorf("Expected the default for values which are not arrays, got %v", ports)
+	}
+
+	server["host"] = "changed"
+	if host := configure.GetString("app.server.host", ""); host != "a:b" {
+		t.Errorf("Expected maps returned by getters to be copies, got %s", host)
+	}
+
+	if raw := configure.GetRaw("app.server.host", nil); raw != "paramsJoin(a, b)" {
+		t.Errorf("Expected the raw value, got %v", raw)
+	}
+	rawServer := configure.GetRaw("app.server", nil).(map[string]interface{})
+	if rawServer["host"] != "paramsJoin(a, b)" {
+		t.Errorf("Expected raw values inside maps, got %v", rawServer["host"])
+	}
+	if raw := configure.GetRaw("app.missing", "def"); raw != "def" {
+		t.Errorf("Expected the default value for missing keys, got %v", raw)
+	}
+}
+
+func TestConfig_EscapedCalls(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_escape")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), `{
+		joined: paramsJoin(a, b)
+		escaped: \paramsJoin(a, b)
+		quoted: "\\paramsJoin(a, b)"
+		prefixed: $paramsJoin(a, b)
+		escapedPrefixed: \$paramsJoin(a, b)
+		path: \\server\share
+	}`)
+
+	for _, index := range []bool{true, false} {
+		opts := []conf.Option{conf.WithConfigDirs(configDir), conf.WithEvaluators(new(testEvalFunction))}
+		if !index {
+			opts = append(opts, conf.WithoutKeyIndex())
+		}
+		configure, err := conf.Load(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkString(configure, "app.joined", "a:b", t)
+		checkString(configure, "app.escaped", "paramsJoin(a, b)", t)
+		checkString(configure, "app.quoted", "paramsJoin(a, b)", t)
+		checkString(configure, "app.prefixed", "$paramsJoin(a, b)", t)
+		checkString(configure, "app.path", `\\server\share`, t)
+		if raw := configure.GetRaw("app.escaped", nil); raw != `\paramsJoin(a, b)` {
+			t.Errorf("Expected the raw value to keep the escape, got %v", raw)
+		}
+
+		configure, err = conf.Load(append(opts, conf.WithEvaluatorPrefix("$"))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkString(configure, "app.joined", "paramsJoin(a, b)", t)
+		checkString(configure, "app.prefixed", "a:b", t)
+		checkString(configure, "app.escapedPrefixed", "$paramsJoin(a, b)", t)
+		if explanation := configure.Explain("app.prefixed"); len(explanation.Layers) != 2 {
+			t.Errorf("Expected an evaluator layer, got %s", explanation)
+		}
+	}
+}
+
 func TestEnvEvaluator_GetFunctionName(t *testing.T) {
 	root, err := os.Executable()
 	if err != nil {
@@ -0,0 +1,202 @@
+package conf
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// KeyProvider supplies the AES key used to encrypt and decrypt config values.
+// Keys must be 16, 24 or 32 bytes long (AES-128, AES-192 or AES-256)
+type KeyProvider interface {
+	// Key returns the raw key bytes or an error if the key is not available
+	Key() ([]byte, error)
+}
+
+// KeyProviderFunc lets an ordinary function act as a KeyProvider,
+// useful when keys come from a vault or any other application service
+type KeyProviderFunc func() ([]byte, error)
+
+var _ KeyProvider = (KeyProviderFunc)(nil)
+
+// Key calls the underlying function
+func (f KeyProviderFunc) Key() ([]byte, error) {
+	return f()
+}
+
+// EnvKeyProvider returns a KeyProvider reading a base64 encoded key, with or without
+// Base64KeyPrefix, from the environment variable name. Used by the evaluator of
+// NewDecryptEvaluator it reads the env of the config, including .env files, like the
+// env evaluator does; used on its own it reads the process environment
+func EnvKeyProvider(name string) KeyProvider {
+	return &envKeyProvider{name: name}
+}
+
+type envKeyProvider struct {
+	name string
+	// env is the env of the snapshot the provider is bound to, nil for the process environment
+	env map[string]string
+}
+
+func (p *envKeyProvider) Key() ([]byte, error) {
+	val := os.Getenv(p.name)
+	if p.env != nil {
+		val = p.env[p.name]
+	}
+	if val == "" {
+		return nil, fmt.Errorf("conf: encryption key variable %s is not set", p.name)
+	}
+	return decodeBase64Key(strings.TrimSpace(val))
+}
+
+// Base64KeyPrefix marks base64 encoded keys in key files, like base64:MDEyMzQ1Njc4OWFi...
+const Base64KeyPrefix = "base64:"
+
+// FileKeyProvider returns a KeyProvider reading the key from the file at path.
+// The file contains the raw key bytes, a trailing newline is ignored, or the base64
+// encoded key prefixed with Base64KeyPrefix. Files without the prefix are always read
+// as raw keys: base64 of 16 and 24 byte keys is 24 and 32 characters long, so it can
+// not be told apart from a raw key and has to be prefixed
+func FileKeyProvider(path string) KeyProvider {
+	return KeyProviderFunc(func() ([]byte, error) {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return decodeFileKey(content)
+	})
+}
+
+// Encrypt seals plaintext with AES-GCM using the key of provider and returns
+// the base64 encoded nonce and ciphertext, ready to be used as enc() argument
+func Encrypt(provider KeyProvider, plaintext string) (string, error) {
+	gcm, err := newGCM(provider)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// EncryptValue is the same as Encrypt but wraps the result in an enc() call
+// so it can be pasted as is into hjson/json files
+func EncryptValue(provider KeyProvider, plaintext string) (string, error) {
+	encrypted, err := Encrypt(provider, plaintext)
+	if err != nil {
+		return "", err
+	}
+	return "enc(\"" + encrypted + "\")", nil
+}
+
+// Decrypt opens a value produced by Encrypt using the key of provider
+func Decrypt(provider KeyProvider, encrypted string) (string, error) {
+	gcm, err := newGCM(provider)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("conf: encrypted value is too short")
+	}
+
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// NewDecryptEvaluator creates the enc() evaluator which decrypts values
+// encrypted with Encrypt at access time using the key of provider.
+// If the value can not be decrypted the default value is returned
+func NewDecryptEvaluator(provider KeyProvider) EvaluatorFunction {
+	return &decryptEvaluator{provider: provider}
+}
+
+type decryptEvaluator struct {
+	provider KeyProvider
+}
+
+var _ EvaluatorFunction = (*decryptEvaluator)(nil)
+
+func (d *decryptEvaluator) GetFunctionName() string {
+	return "enc"
+}
+
+func (d *decryptEvaluator) Eval(params []string, def interface{}) interface{} {
+	if len(params) != 1 {
+		return def
+	}
+
+	plain, err := Decrypt(d.provider, params[0])
+	if err != nil {
+		return def
+	}
+	return plain
+}
+
+func newGCM(provider KeyProvider) (cipher.AEAD, error) {
+	if provider == nil {
+		return nil, errors.New("conf: no encryption key provider")
+	}
+
+	key, err := provider.Key()
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+var (
+	errKeySize     = errors.New("conf: encryption key must be 16, 24 or 32 bytes long")
+	errFileKeySize = errors.New("conf: key file must contain a raw key of 16, 24 or 32 bytes or a base64 key prefixed with " + Base64KeyPrefix)
+)
+
+// decodeFileKey reads a raw key, or a base64 key marked with Base64KeyPrefix
+func decodeFileKey(content []byte) ([]byte, error) {
+	trimmed := strings.TrimSpace(string(content))
+	switch {
+	case strings.HasPrefix(trimmed, Base64KeyPrefix):
+		return decodeBase64Key(trimmed)
+	case validKeySize(len(content)):
+		return content, nil
+	case validKeySize(len(trimmed)):
+		return []byte(trimmed), nil
+	}
+	return nil, errFileKeySize
+}
+
+// decodeBase64Key decodes value, with or without Base64KeyPrefix
+func decodeBase64Key(value string) ([]byte, error) {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, Base64KeyPrefix))
+	if err != nil || !validKeySize(len(decoded)) {
+		return nil, errKeySize
+	}
+	return decoded, nil
+}
+
+func validKeySize(size int) bool {
+	return size == 16 || size == 24 || size == 32
+}
@@ -0,0 +1,136 @@
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testKeyProvider = conf.KeyProviderFunc(func() ([]byte, error) {
+	return []byte("0123456789abcdef0123456789abcdef"), nil
+})
+
+func TestDecryptEvaluator(t *testing.T) {
+	root, err := os.Executable()
+	if err != nil {
+		t.Error(err)
+	}
+
+	rootDir := filepath.Join(filepath.Dir(root), "..")
+	t.Log("Searching Config files at: " + rootDir)
+
+	configure, err := conf.New(filepath.Join(rootDir, "test_configs/valids"), rootDir, []conf.EvaluatorFunction{
+		conf.NewDecryptEvaluator(testKeyProvider),
+	})
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	checkString(configure, "secrets.password", "top secret", t)
+	checkString(configure, "secrets.corrupted", "not found", t)
+}
+
+func TestDecryptEvaluator_EnvFileKey(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_env_key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	value, err := conf.EncryptValue(testKeyProvider, "from env file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeConfigFile(t, filepath.Join(configDir, ".env"), "CONF_TEST_ENV_FILE_KEY=MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=\n")
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), "password: "+value)
+
+	configure, err := conf.New(configDir, configDir, []conf.EvaluatorFunction{
+		conf.NewDecryptEvaluator(conf.EnvKeyProvider("CONF_TEST_ENV_FILE_KEY")),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkString(configure, "app.password", "from env file", t)
+	if _, ok := os.LookupEnv("CONF_TEST_ENV_FILE_KEY"); ok {
+		t.Error("Expected the key to stay out of the process environment")
+	}
+}
+
+func TestEncryptValue(t *testing.T) {
+	value, err := conf.EncryptValue(testKeyProvider, "round trip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(value, "enc(\"") || !strings.HasSuffix(value, "\")") {
+		t.Errorf("Unexpected encrypted value format: %s", value)
+	}
+
+	plain, err := conf.Decrypt(testKeyProvider, strings.TrimSuffix(strings.TrimPrefix(value, "enc(\""), "\")"))
+	if err != nil || plain != "round trip" {
+		t.Errorf("Failed to decrypt encrypted value, got \"%s\" with error %v", plain, err)
+	}
+
+	if _, err = conf.Encrypt(conf.KeyProviderFunc(func() ([]byte, error) {
+		return []byte("short"), nil
+	}), "value"); err == nil {
+		t.Error("Expected an error for an invalid key size")
+	}
+}
+
+func TestKeyProviders(t *testing.T) {
+	os.Setenv("CONF_TEST_KEY", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	defer os.Unsetenv("CONF_TEST_KEY")
+
+	key, err := conf.EnvKeyProvider("CONF_TEST_KEY").Key()
+	if err != nil || string(key) != "0123456789abcdef0123456789abcdef" {
+		t.Errorf("Failed reading key from environment: %v", err)
+	}
+
+	if _, err = conf.EnvKeyProvider("CONF_TEST_KEY_MISSING").Key(); err == nil {
+		t.Error("Expected an error for a missing key variable")
+	}
+
+	if _, err = conf.FileKeyProvider("does not exist.key").Key(); err == nil {
+		t.Error("Expected an error for a missing key file")
+	}
+
+	keyDir, err := ioutil.TempDir("", "conf_keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(keyDir)
+
+	for content, expected := range map[string]string{
+		"0123456789abcdef0123456789abcdef":                      "0123456789abcdef0123456789abcdef",
+		"0123456789abcdef0123456789abcdef\n":                    "0123456789abcdef0123456789abcdef",
+		"0123456789abcdef":                                      "0123456789abcdef",
+		"base64:MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=\n": "0123456789abcdef0123456789abcdef",
+		"base64:MDEyMzQ1Njc4OWFiY2RlZg==":                       "0123456789abcdef",
+		"base64:MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3":               "0123456789abcdef01234567",
+		// unprefixed base64 of 16 and 24 byte keys has the length of a raw key
+		"MDEyMzQ1Njc4OWFiY2RlZg==":                       "MDEyMzQ1Njc4OWFiY2RlZg==",
+		"MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3\n":             "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3",
+		"MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=\n": "",
+		"0123456789abcdef0123456789abcdef0":              "",
+		"base64:not a key!":                              "",
+	} {
+		keyFile := filepath.Join(keyDir, "key")
+		if err = ioutil.WriteFile(keyFile, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		key, err := conf.FileKeyProvider(keyFile).Key()
+		if expected == "" {
+			if err == nil {
+				t.Errorf("Expected an error for key file %q, got key %q", content, key)
+			}
+			continue
+		}
+		if err != nil || string(key) != expected {
+			t.Errorf("Expected key %q from key file %q, got %q %v", expected, content, key, err)
+		}
+	}
+}
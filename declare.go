@@ -0,0 +1,131 @@
+package conf
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Declaration describes a config key a package depends on,
+// see Declare and Config.Check
+type Declaration struct {
+	Key      string
+	Required bool
+	// Type is the go type the value must be convertible to, nil accepts any value
+	Type reflect.Type
+}
+
+// DeclareOption configures a Declaration
+type DeclareOption func(d *Declaration)
+
+// Required declares that the key must exist
+var Required DeclareOption = func(d *Declaration) {
+	d.Required = true
+}
+
+// TypeOf declares that the value of the key must be convertible to the type of example,
+// e.g. TypeOf(0) for integers or TypeOf(time.Duration(0)) for durations.
+// With Go 1.18 or newer Type[T] can be used instead
+func TypeOf(example interface{}) DeclareOption {
+	return func(d *Declaration) {
+		d.Type = reflect.TypeOf(example)
+	}
+}
+
+var (
+	declarationsMu sync.Mutex
+	declarations   = make(map[string]*Declaration)
+)
+
+// Declare registers key as a dependency of the calling package, usually from an init function.
+// Declaring the same key again applies the new options to the existing declaration.
+// Config.Check reports all declared keys which are missing or have a wrong type at once
+//
+//	func init() {
+//		conf.Declare("app.database.password", conf.Required, conf.Type[string])
+//	}
+func Declare(key string, options ...DeclareOption) {
+	declarationsMu.Lock()
+	defer declarationsMu.Unlock()
+
+	d, ok := declarations[key]
+	if !ok {
+		d = &Declaration{Key: key}
+		declarations[key] = d
+	}
+	for _, option := range options {
+		option(d)
+	}
+}
+
+// Declarations returns all declared keys sorted by key
+func Declarations() []Declaration {
+	declarationsMu.Lock()
+	defer declarationsMu.Unlock()
+
+	list := make([]Declaration, 0, len(declarations))
+	for _, d := range declarations {
+		list = append(list, *d)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
+
+// Require reports every key of keys which does not exist, as ValidationErrors
+func (c *Config) Require(keys ...string) error {
+	s := c.load()
+
+	var errs ValidationErrors
+	for _, key := range keys {
+		key = c.canonicalKey(key)
+		if get(s, key, nil) == nil {
+			errs = append(errs, &ValidationError{Key: key, Message: "required key is missing"})
+		}
+	}
+	if len(errs) > 0 {
+		c.reportErrors(s, errs)
+		return errs
+	}
+	return nil
+}
+
+// Check validates the configs against all declarations registered with Declare,
+// reporting every missing required key and every value which can not be converted
+// to its declared type at once, as ValidationErrors
+func (c *Config) Check() error {
+	s := c.load()
+
+	var errs ValidationErrors
+	for _, d := range Declarations() {
+		key := c.canonicalKey(d.Key)
+		value := get(s, key, nil)
+		if value == nil {
+			if d.Required {
+				errs = append(errs, &ValidationError{Key: key, Message: "required key is missing"})
+			}
+			continue
+		}
+
+		if d.Type != nil {
+			dec := new(decoder)
+			dec.decode(key, value, reflect.New(d.Type).Elem())
+			errs = append(errs, dec.errs...)
+		}
+	}
+	if len(errs) > 0 {
+		c.reportErrors(s, errs)
+		return errs
+	}
+	return nil
+}
+
+// reportErrors sets the source files of errs with canonical keys and converts
+// their keys to the key delimiter of the config
+func (c *Config) reportErrors(s *snapshot, errs ValidationErrors) {
+	s.addSourceFiles(errs)
+	for _, err := range errs {
+		err.Key = c.externalKey(err.Key)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build go1.18
+// +build go1.18
+
+package conf
+
+import "reflect"
+
+// Type declares that the value of the key must be convertible to T
+//
+//	conf.Declare("app.server.port", conf.Type[int])
+func Type[T any](d *Declaration) {
+	d.Type = reflect.TypeOf((*T)(nil)).Elem()
+}
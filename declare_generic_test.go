@@ -0,0 +1,25 @@
+//go:build go1.18
+// +build go1.18
+
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"reflect"
+	"testing"
+)
+
+func TestType(t *testing.T) {
+	defer conf.ResetDeclarations()
+	conf.Declare("generic.declared.key", conf.Type[map[string]int])
+
+	for _, d := range conf.Declarations() {
+		if d.Key == "generic.declared.key" {
+			if d.Required || d.Type != reflect.TypeOf(map[string]int{}) {
+				t.Errorf("Unexpected declaration %+v", d)
+			}
+			return
+		}
+	}
+	t.Error("Declared key not found")
+}
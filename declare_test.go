@@ -0,0 +1,79 @@
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfig_Require(t *testing.T) {
+	root, err := os.Executable()
+	if err != nil {
+		t.Error(err)
+	}
+
+	rootDir := filepath.Join(filepath.Dir(root), "..")
+	t.Log("Searching Config files at: " + rootDir)
+
+	configure, err := conf.New(filepath.Join(rootDir, "test_configs/valids"), rootDir, []conf.EvaluatorFunction{
+		new(testEvalFunction),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = configure.Require("nested.vars.app.inner.string", "evaluators.env.port"); err != nil {
+		t.Error(err)
+	}
+
+	err = configure.Require("nested.vars.app.inner.string", "nested.vars.missing", "do.not.exist")
+	errs, ok := err.(conf.ValidationErrors)
+	if !ok || len(errs) != 2 || errs[0].Key != "nested.vars.missing" || errs[1].Key != "do.not.exist" {
+		t.Errorf("Expected two missing keys, got %v", err)
+	}
+}
+
+func TestConfig_Check(t *testing.T) {
+	root, err := os.Executable()
+	if err != nil {
+		t.Error(err)
+	}
+
+	rootDir := filepath.Join(filepath.Dir(root), "..")
+	t.Log("Searching Config files at: " + rootDir)
+
+	configure, err := conf.New(filepath.Join(rootDir, "test_configs/valids"), rootDir, []conf.EvaluatorFunction{
+		new(testEvalFunction),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conf.ResetDeclarations()
+	conf.Declare("evaluators.env.port", conf.Required, conf.TypeOf(0))
+	conf.Declare("nested.vars.intArray", conf.TypeOf([]int{}))
+	conf.Declare("nested.vars.optional", conf.TypeOf(""))
+	if err = configure.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	conf.Declare("nested.vars.app.inner.string", conf.TypeOf(time.Duration(0)))
+	conf.Declare("nested.vars.required", conf.Required)
+	conf.Declare("nested.vars.optional", conf.Required)
+
+	err = configure.Check()
+	errs, ok := err.(conf.ValidationErrors)
+	if !ok || len(errs) != 3 {
+		t.Fatalf("Expected three violations, got %v", err)
+	}
+	t.Log(err)
+
+	expected := []string{"nested.vars.app.inner.string", "nested.vars.optional", "nested.vars.required"}
+	for index, violation := range errs {
+		if violation.Key != expected[index] {
+			t.Errorf("Expected violation for %s, got %s", expected[index], violation)
+		}
+	}
+}
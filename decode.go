@@ -0,0 +1,297 @@
+package conf
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// decoder converts resolved config values (as produced by hjson: string, float64,
+// bool, []interface{} and map[string]interface{}) into go values, collecting
+// every failure with the dotted config key it happened at
+type decoder struct {
+	s     *snapshot
+	usage *usageTracker
+	errs  ValidationErrors
+}
+
+// decodeValue converts value into target and returns the first failure
+func decodeValue(value interface{}, target reflect.Value) error {
+	d := new(decoder)
+	d.decode("", value, target)
+	if len(d.errs) > 0 {
+		return d.errs[0]
+	}
+	return nil
+}
+
+func (d *decoder) fail(key string, format string, args ...interface{}) {
+	err := &ValidationError{Key: key, Message: fmt.Sprintf(format, args...)}
+	if d.s != nil {
+		err.File = d.s.sourceFile(key)
+	}
+	d.errs = append(d.errs, err)
+}
+
+func (d *decoder) typeError(key string, value interface{}, target reflect.Type) {
+	d.fail(key, "can not use %T value %v as %s", value, value, target)
+}
+
+func (d *decoder) decode(key string, value interface{}, target reflect.Value) {
+	if value == nil {
+		// missing values keep defaults set by the caller
+		if target.Kind() == reflect.Struct {
+			// still report required fields of missing objects
+			d.decodeStruct(key, nil, target)
+		}
+		return
+	}
+
+	if kind := target.Kind(); kind != reflect.Struct && kind != reflect.Ptr {
+		d.usage.record(key)
+	}
+
+	if target.Type() == durationType {
+		d.decodeDuration(key, value, target)
+		return
+	}
+
+	switch target.Kind() {
+	case reflect.Interface:
+		if !reflect.TypeOf(value).AssignableTo(target.Type()) {
+			d.typeError(key, value, target.Type())
+			return
+		}
+		target.Set(reflect.ValueOf(value))
+	case reflect.Ptr:
+		elem := reflect.New(target.Type().Elem())
+		d.decode(key, value, elem.Elem())
+		target.Set(elem)
+	case reflect.String:
+		switch typed := value.(type) {
+		case string:
+			target.SetString(typed)
+		case float64:
+			target.SetString(strconv.FormatFloat(typed, 'f', -1, 64))
+		case bool:
+			target.SetString(strconv.FormatBool(typed))
+		default:
+			d.typeError(key, value, target.Type())
+		}
+	case reflect.Bool:
+		switch typed := value.(type) {
+		case bool:
+			target.SetBool(typed)
+		case float64:
+			if typed != 0 && typed != 1 {
+				d.typeError(key, value, target.Type())
+				return
+			}
+			target.SetBool(typed == 1)
+		case string:
+			parsed, err := strconv.ParseBool(typed)
+			if err != nil {
+				d.typeError(key, value, target.Type())
+				return
+			}
+			target.SetBool(parsed)
+		default:
+			d.typeError(key, value, target.Type())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, err := toFloat(value)
+		if err != nil || number != math.Trunc(number) {
+			d.typeError(key, value, target.Type())
+			return
+		}
+		if number < math.MinInt64 || number >= math.MaxInt64 || target.OverflowInt(int64(number)) {
+			d.fail(key, "value %v overflows %s", value, target.Type())
+			return
+		}
+		target.SetInt(int64(number))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number, err := toFloat(value)
+		if err != nil || number != math.Trunc(number) {
+			d.typeError(key, value, target.Type())
+			return
+		}
+		if number < 0 || number >= math.MaxUint64 || target.OverflowUint(uint64(number)) {
+			d.fail(key, "value %v overflows %s", value, target.Type())
+			return
+		}
+		target.SetUint(uint64(number))
+	case reflect.Float32, reflect.Float64:
+		number, err := toFloat(value)
+		if err != nil {
+			d.typeError(key, value, target.Type())
+			return
+		}
+		if target.OverflowFloat(number) {
+			d.fail(key, "value %v overflows %s", value, target.Type())
+			return
+		}
+		target.SetFloat(number)
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			d.typeError(key, value, target.Type())
+			return
+		}
+		slice := reflect.MakeSlice(target.Type(), len(items), len(items))
+		for index, item := range items {
+			d.decode(indexKey(key, index), item, slice.Index(index))
+		}
+		target.Set(slice)
+	case reflect.Map:
+		items, ok := value.(map[string]interface{})
+		if !ok || target.Type().Key().Kind() != reflect.String {
+			d.typeError(key, value, target.Type())
+			return
+		}
+		result := reflect.MakeMapWithSize(target.Type(), len(items))
+		for name, item := range items {
+			elem := reflect.New(target.Type().Elem()).Elem()
+			d.decode(joinKey(key, name), item, elem)
+			result.SetMapIndex(reflect.ValueOf(name).Convert(target.Type().Key()), elem)
+		}
+		target.Set(result)
+	case reflect.Struct:
+		items, ok := value.(map[string]interface{})
+		if !ok {
+			d.typeError(key, value, target.Type())
+			return
+		}
+		d.decodeStruct(key, items, target)
+	default:
+		d.typeError(key, value, target.Type())
+	}
+}
+
+// decodeStruct fills exported fields of target from items, fields are matched by their
+// conf tag or case insensitively by their name, embedded structs share the keys of their parent
+func (d *decoder) decodeStruct(key string, items map[string]interface{}, target reflect.Value) {
+	targetType := target.Type()
+	for index := 0; index < targetType.NumField(); index++ {
+		field := targetType.Field(index)
+		name, skip := fieldKey(field)
+		if skip {
+			continue
+		}
+
+		if structType, ok := inlinedStruct(field); ok {
+			embedded := target.Field(index)
+			if embedded.Kind() == reflect.Ptr {
+				if embedded.IsNil() {
+					// like encoding/json, pointers are only allocated for keys they hold
+					if !coversAny(structType, items) {
+						continue
+					}
+					embedded.Set(reflect.New(structType))
+				}
+				embedded = embedded.Elem()
+			}
+			d.decodeStruct(key, items, embedded)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		matched, value, found := lookupField(items, name)
+		if found {
+			name = matched
+		}
+		failures := len(d.errs)
+		d.decode(joinKey(key, name), value, target.Field(index))
+		if len(d.errs) > failures {
+			continue
+		}
+		d.validateField(joinKey(key, name), field, found && value != nil, target.Field(index))
+	}
+}
+
+func (d *decoder) decodeDuration(key string, value interface{}, target reflect.Value) {
+	switch typed := value.(type) {
+	case string:
+		duration, err := time.ParseDuration(typed)
+		if err != nil {
+			d.typeError(key, value, target.Type())
+			return
+		}
+		target.SetInt(int64(duration))
+	case float64:
+		target.SetInt(int64(typed))
+	default:
+		d.typeError(key, value, target.Type())
+	}
+}
+
+// inlinedStruct returns the struct type of an untagged embedded field whose fields share the
+// keys of its parent: an embedded struct, or an exported embedded pointer to a struct
+func inlinedStruct(field reflect.StructField) (reflect.Type, bool) {
+	if !field.Anonymous || field.Tag.Get("conf") != "" {
+		return nil, false
+	}
+	switch {
+	case field.Type.Kind() == reflect.Struct:
+		return field.Type, true
+	case field.PkgPath == "" && field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct:
+		return field.Type.Elem(), true
+	}
+	return nil, false
+}
+
+// coversAny reports if decoding items into target would read any of them
+func coversAny(target reflect.Type, items map[string]interface{}) bool {
+	for name := range items {
+		if typeCovers(target, []string{name}) {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldKey returns the config key name of a struct field: the name of its conf tag, or
+// its name starting with a lower case letter as config keys usually do
+func fieldKey(field reflect.StructField) (name string, skip bool) {
+	tag := field.Tag.Get("conf")
+	if tag == "-" {
+		return "", true
+	}
+	if tag != "" {
+		return strings.Split(tag, ",")[0], false
+	}
+	first, size := utf8.DecodeRuneInString(field.Name)
+	return string(unicode.ToLower(first)) + field.Name[size:], false
+}
+
+// lookupField finds name in items, trying an exact match before a case insensitive one
+func lookupField(items map[string]interface{}, name string) (string, interface{}, bool) {
+	if value, found := items[name]; found {
+		return name, value, true
+	}
+	for key, value := range items {
+		if strings.EqualFold(key, name) {
+			return key, value, true
+		}
+	}
+	return name, nil, false
+}
+
+func toFloat(value interface{}) (float64, error) {
+	switch typed := value.(type) {
+	case float64:
+		return typed, nil
+	case string:
+		return strconv.ParseFloat(typed, 64)
+	default:
+		return 0, fmt.Errorf("%v is not a number", value)
+	}
+}
@@ -0,0 +1,275 @@
+package conf
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// envLoader builds an env from .env files. Files support `export` prefixes, comments,
+// single quoted literal values, double quoted values with escapes spanning several lines
+// and ${NAME}, ${NAME:-default} and $NAME expansion in unquoted and double quoted values
+type envLoader struct {
+	env map[string]string
+	// sources maps each variable to the file defining it, process variables have no source
+	sources map[string]string
+	// processEnv makes expansion fall back to the process environment
+	processEnv bool
+}
+
+func newEnvLoader(processEnv bool) *envLoader {
+	return &envLoader{
+		env:        make(map[string]string),
+		sources:    make(map[string]string),
+		processEnv: processEnv,
+	}
+}
+
+// readFile parses the env file at path and adds its variables to the env
+func (l *envLoader) readFile(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return l.parse(path, string(content))
+}
+
+// set adds a variable to the env, source is empty for process variables
+func (l *envLoader) set(name string, value string, source string) {
+	l.env[name] = value
+	if source == "" {
+		delete(l.sources, name)
+	} else {
+		l.sources[name] = source
+	}
+}
+
+func (l *envLoader) lookup(name string) (string, bool) {
+	if value, ok := l.env[name]; ok {
+		return value, true
+	}
+	if l.processEnv {
+		return os.LookupEnv(name)
+	}
+	return "", false
+}
+
+// envParser holds the position of the parser inside a single env file
+type envParser struct {
+	loader *envLoader
+	file   string
+	text   string
+	pos    int
+}
+
+func (l *envLoader) parse(file string, text string) error {
+	p := &envParser{loader: l, file: file, text: text}
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.text) {
+			return nil
+		}
+
+		start := p.pos
+		name := p.name()
+		if name == "export" && p.peekSpace() {
+			p.skipSpaces()
+			start = p.pos
+			name = p.name()
+		}
+		if name == "" {
+			return p.errorAt(start, "expected a variable name")
+		}
+
+		p.skipSpaces()
+		if p.pos >= len(p.text) || p.text[p.pos] != '=' {
+			return p.errorAt(p.pos, "expected = after "+name)
+		}
+		p.pos++
+		p.skipSpaces()
+
+		value, err := p.value()
+		if err != nil {
+			return err
+		}
+		l.set(name, value, file)
+	}
+}
+
+func (p *envParser) skipBlank() {
+	for p.pos < len(p.text) {
+		switch p.text[p.pos] {
+		case ' ', '\t', '\r', '\n':
+			p.pos++
+		case '#':
+			p.skipLine()
+		default:
+			return
+		}
+	}
+}
+
+func (p *envParser) skipSpaces() {
+	for p.pos < len(p.text) && (p.text[p.pos] == ' ' || p.text[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *envParser) skipLine() {
+	if end := strings.IndexByte(p.text[p.pos:], '\n'); end >= 0 {
+		p.pos += end + 1
+	} else {
+		p.pos = len(p.text)
+	}
+}
+
+func (p *envParser) peekSpace() bool {
+	return p.pos < len(p.text) && (p.text[p.pos] == ' ' || p.text[p.pos] == '\t')
+}
+
+func (p *envParser) name() string {
+	start := p.pos
+	for p.pos < len(p.text) && (isEnvNameChar(p.text[p.pos]) || p.text[p.pos] == '.' || p.text[p.pos] == '-') {
+		p.pos++
+	}
+	return p.text[start:p.pos]
+}
+
+// value parses the value after = up to the end of its line
+func (p *envParser) value() (string, error) {
+	if p.pos >= len(p.text) {
+		return "", nil
+	}
+
+	switch quote := p.text[p.pos]; quote {
+	case '\'', '"':
+		start := p.pos
+		p.pos++
+		end := p.pos
+		for ; end < len(p.text) && p.text[end] != quote; end++ {
+			if quote == '"' && p.text[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(p.text) {
+			return "", p.errorAt(start, "unterminated quoted value")
+		}
+
+		raw := p.text[p.pos:end]
+		rawPos := p.pos
+		p.pos = end + 1
+		p.skipSpaces()
+		if p.pos < len(p.text) && p.text[p.pos] != '\n' && p.text[p.pos] != '\r' && p.text[p.pos] != '#' {
+			return "", p.errorAt(p.pos, "unexpected character after quoted value")
+		}
+		p.skipLine()
+
+		if quote == '\'' {
+			return raw, nil
+		}
+		return p.expand(raw, rawPos, true)
+
+	default:
+		start := p.pos
+		end := strings.IndexByte(p.text[start:], '\n')
+		if end < 0 {
+			end = len(p.text)
+		} else {
+			end += start
+		}
+		raw := p.text[start:end]
+		// a # preceded by a space starts a comment
+		for index := 1; index < len(raw); index++ {
+			if raw[index] == '#' && (raw[index-1] == ' ' || raw[index-1] == '\t') {
+				raw = raw[:index]
+				break
+			}
+		}
+		p.pos = end
+		return p.expand(strings.TrimSpace(raw), start, false)
+	}
+}
+
+// expand replaces variable references of raw, which starts at offset of the file.
+// Escape sequences are only processed in double quoted values
+func (p *envParser) expand(raw string, offset int, escapes bool) (string, error) {
+	var builder strings.Builder
+	for index := 0; index < len(raw); index++ {
+		char := raw[index]
+		switch {
+		case char == '\\' && escapes && index+1 < len(raw):
+			index++
+			switch raw[index] {
+			case 'n':
+				builder.WriteByte('\n')
+			case 'r':
+				builder.WriteByte('\r')
+			case 't':
+				builder.WriteByte('\t')
+			default:
+				builder.WriteByte(raw[index])
+			}
+
+		case char == '$' && index+1 < len(raw) && raw[index+1] == '{':
+			end := strings.IndexByte(raw[index:], '}')
+			if end < 0 {
+				return "", p.errorAt(offset+index, "unterminated variable reference")
+			}
+			reference := raw[index+2 : index+end]
+			name, def, hasDefault := reference, "", false
+			if separator := strings.Index(reference, ":-"); separator >= 0 {
+				name, def, hasDefault = reference[:separator], reference[separator+2:], true
+			}
+
+			value, _ := p.loader.lookup(name)
+			if value == "" && hasDefault {
+				var err error
+				if value, err = p.expand(def, offset+index+2+len(name)+2, escapes); err != nil {
+					return "", err
+				}
+			}
+			builder.WriteString(value)
+			index += end
+
+		case char == '$' && index+1 < len(raw) && isEnvNameChar(raw[index+1]) && !isDigit(raw[index+1]):
+			end := index + 1
+			for end < len(raw) && isEnvNameChar(raw[end]) {
+				end++
+			}
+			value, _ := p.loader.lookup(raw[index+1 : end])
+			builder.WriteString(value)
+			index = end - 1
+
+		default:
+			builder.WriteByte(char)
+		}
+	}
+	return builder.String(), nil
+}
+
+func (p *envParser) errorAt(offset int, message string) *ParseError {
+	line := strings.Count(p.text[:offset], "\n") + 1
+	lineStart := strings.LastIndexByte(p.text[:offset], '\n') + 1
+	lineEnd := strings.IndexByte(p.text[lineStart:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(p.text)
+	} else {
+		lineEnd += lineStart
+	}
+
+	return &ParseError{
+		File:    p.file,
+		Line:    line,
+		Column:  offset - lineStart + 1,
+		Message: message,
+		Snippet: strings.TrimRight(p.text[lineStart:lineEnd], "\r"),
+	}
+}
+
+func isEnvNameChar(char byte) bool {
+	return char == '_' || isDigit(char) || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z')
+}
+
+func isDigit(char byte) bool {
+	return char >= '0' && char <= '9'
+}
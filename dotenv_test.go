@@ -0,0 +1,69 @@
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_EnvFileSyntax(t *testing.T) {
+	envDir, err := ioutil.TempDir("", "conf_dotenv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(envDir)
+
+	os.Setenv("CONF_TEST_DOTENV_USER", "admin")
+	defer os.Unsetenv("CONF_TEST_DOTENV_USER")
+
+	writeConfigFile(t, filepath.Join(envDir, ".env"), `# database settings
+export DB_HOST=localhost
+DB_PORT = 5432 # inline comment
+DB_URL=postgres://${CONF_TEST_DOTENV_USER}@$DB_HOST:${DB_PORT}/app
+DB_NAME=${DB_MISSING:-fallback}
+LITERAL='${DB_HOST} # not a comment'
+CERT="-----BEGIN-----
+line\tone
+-----END-----"
+ESCAPED="say \"hi\" \$DB_HOST"
+EMPTY=
+`)
+	writeConfigFile(t, filepath.Join(envDir, ".env.test"), "DB_PORT=6543\nTEST_URL=$DB_HOST:$DB_PORT\n")
+
+	configure, err := conf.New(envDir, envDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"DB_HOST":  "localhost",
+		"DB_PORT":  "6543",
+		"DB_URL":   "postgres://admin@localhost:5432/app",
+		"DB_NAME":  "fallback",
+		"LITERAL":  "${DB_HOST} # not a comment",
+		"CERT":     "-----BEGIN-----\nline\tone\n-----END-----",
+		"ESCAPED":  `say "hi" $DB_HOST`,
+		"EMPTY":    "",
+		"TEST_URL": "localhost:6543",
+	}
+	for name, value := range expected {
+		if actual, ok := configure.LookupEnv(name); !ok || actual != value {
+			t.Errorf("Expected %s to be %q, got %q", name, value, actual)
+		}
+	}
+
+	if file, ok := configure.EnvSource("DB_PORT"); !ok || file != filepath.Join(envDir, ".env.test") {
+		t.Errorf("Expected DB_PORT to come from .env.test, got %s", file)
+	}
+	if file, ok := configure.EnvSource("CONF_TEST_DOTENV_USER"); !ok || file != "" {
+		t.Errorf("Expected a process variable without file, got %s", file)
+	}
+
+	writeConfigFile(t, filepath.Join(envDir, ".env"), "VALID=1\nBROKEN=\"unterminated\n")
+	_, err = conf.New(envDir, envDir, nil)
+	if parseErr, ok := err.(*conf.ParseError); !ok || parseErr.Line != 2 || parseErr.Column != 8 {
+		t.Errorf("Expected a parse error at 2:8, got %v", err)
+	}
+}
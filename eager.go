@@ -0,0 +1,101 @@
+package conf
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CheckedEvaluator can be implemented by evaluators to report why they have no value.
+// With eager evaluation loading fails with the reported error. Evaluators not implementing
+// it are called with a nil default value when evaluated eagerly or cached, and have no
+// value when they return nil
+type CheckedEvaluator interface {
+	// CheckedEval returns the result of Eval(params) or the reason there is none
+	CheckedEval(params []string) (interface{}, error)
+}
+
+// evalChecked calls evaluator without a default value, it returns an error when there is no value
+func evalChecked(name string, evaluator EvaluatorFunction, params []string) (interface{}, error) {
+	if checked, ok := evaluator.(CheckedEvaluator); ok {
+		return checked.CheckedEval(params)
+	}
+	if value := evaluator.Eval(params, nil); value != nil {
+		return value, nil
+	}
+	return nil, fmt.Errorf("evaluator %s has no value for %v", name, params)
+}
+
+// evaluateEager returns a copy of configs with evaluator calls replaced by their results and
+// escaped literal values unescaped, and the canonical keys of these values, which are read as
+// they are. Calls of lazy evaluators and evaluators whose results expire (see CachePolicy) are kept
+func evaluateEager(s *snapshot, lazy map[string]bool) (map[string]interface{}, map[string]bool, ValidationErrors) {
+	var errs ValidationErrors
+	literals := make(map[string]bool)
+
+	var walk func(key string, value interface{}) interface{}
+	walk = func(key string, value interface{}) interface{} {
+		switch typed := value.(type) {
+		case map[string]interface{}:
+			resolved := make(map[string]interface{}, len(typed))
+			for name, item := range typed {
+				resolved[name] = walk(joinKey(key, name), item)
+			}
+			return resolved
+		case []interface{}:
+			resolved := make([]interface{}, len(typed))
+			for position, item := range typed {
+				resolved[position] = walk(indexKey(key, position), item)
+			}
+			return resolved
+		case string:
+			methodName, params, ok := parseCall(s, typed)
+			if !ok {
+				if unescaped := unescapeCall(s, typed); unescaped != typed {
+					literals[key] = true
+					return unescaped
+				}
+				return typed
+			}
+			if lazy[methodName] {
+				return typed
+			}
+			evaluator := s.evaluators[methodName]
+			if policy, ok := evaluator.(CachePolicy); ok && policy.CacheTTL() >= 0 {
+				return typed
+			}
+
+			result, err := evalChecked(methodName, evaluator, params)
+			if err != nil {
+				errs = append(errs, &ValidationError{Key: key, Message: err.Error()})
+				return typed
+			}
+			literals[key] = true
+			return result
+		default:
+			return value
+		}
+	}
+	configs := walk("", s.configs).(map[string]interface{})
+
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Key < errs[j].Key
+	})
+	return configs, literals, errs
+}
+
+// literalKey returns the canonical key of steps with negative indexes made absolute,
+// the form of the keys of the literals of s
+func (s *snapshot) literalKey(steps []pathStep) string {
+	absolute := make([]pathStep, len(steps))
+	copy(absolute, steps)
+
+	var value interface{} = s.configs
+	for position, step := range absolute {
+		if step.kind == stepIndex {
+			array, _ := value.([]interface{})
+			absolute[position].index, _ = arrayIndex(array, step.index)
+		}
+		value, _ = lookupSteps(value, absolute[position:position+1], s.keyIndex)
+	}
+	return formatPath(absolute, ".")
+}
@@ -0,0 +1,209 @@
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfig_EagerEvaluation(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_eager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, ".env"), "HOST=example.com\n")
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), `{
+		server: {
+			host: env(HOST)
+			joined: paramsJoin(a, b)
+			counted: counter()
+		}
+		timed: timedCounter()
+	}`)
+
+	counter := new(countingEvaluator)
+	timed := &timedCountingEvaluator{cachedCountingEvaluator{ttl: time.Hour}}
+	configure, err := conf.Load(
+		conf.WithConfigDirs(configDir),
+		conf.WithEnvDirs(configDir),
+		conf.WithProcessEnv(false),
+		conf.WithEvaluators(new(testEvalFunction), counter, timed),
+		conf.WithEagerEvaluation(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := configure.ConfigsMap()["app"].(map[string]interface{})["server"].(map[string]interface{})
+	if server["host"] != "example.com" || server["joined"] != "a:b" || server["counted"] != 1.0 {
+		t.Errorf("Expected ConfigsMap to hold evaluated values, got %v", server)
+	}
+	for i := 0; i < 2; i++ {
+		if counted := configure.GetInt("app.server.counted", 0); counted != 1 {
+			t.Errorf("Expected the evaluator to be called once on load, got %d", counted)
+		}
+	}
+	if timed := configure.ConfigsMap()["app"].(map[string]interface{})["timed"]; timed != "timedCounter()" {
+		t.Errorf("Expected evaluators with expiring results to stay lazy, got %v", timed)
+	}
+	if explanation := configure.Explain("app.server.host"); len(explanation.Layers) != 3 || explanation.Layers[1].Source != "env(HOST)" {
+		t.Errorf("Expected the evaluator layer in the explanation, got %s", explanation)
+	}
+
+	configure, err = conf.Load(
+		conf.WithConfigDirs(configDir),
+		conf.WithEnvDirs(configDir),
+		conf.WithEvaluators(new(testEvalFunction), new(countingEvaluator), timed),
+		conf.WithEagerEvaluation(),
+		conf.WithCaseInsensitiveKeys(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkString(configure, "APP.Server.Joined", "a:b", t)
+
+	counter = new(countingEvaluator)
+	configure, err = conf.Load(
+		conf.WithConfigDirs(configDir),
+		conf.WithEnvDirs(configDir),
+		conf.WithEvaluators(new(testEvalFunction), counter, timed),
+		conf.WithEagerEvaluation("counter"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counted := configure.GetInt("app.server.counted", 0); counted != 1 {
+		t.Errorf("Expected the lazy evaluator to be called on read, got %d", counted)
+	}
+	if counted := configure.GetInt("app.server.counted", 0); counted != 2 {
+		t.Errorf("Expected the lazy evaluator to be called on each read, got %d", counted)
+	}
+
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), `{
+		server: {
+			host: env(HOST)
+			port: env(PORT)
+		}
+	}`)
+	_, err = conf.Load(
+		conf.WithConfigDirs(configDir),
+		conf.WithEnvDirs(configDir),
+		conf.WithProcessEnv(false),
+		conf.WithEagerEvaluation(),
+	)
+	errs, ok := err.(conf.ValidationErrors)
+	if !ok || len(errs) != 1 || errs[0].Key != "app.server.port" || !strings.Contains(errs[0].Message, "PORT") {
+		t.Errorf("Expected an error for the missing variable, got %v", err)
+	}
+}
+
+func TestConfig_EagerResultsAreNotEvaluatedAgain(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_eager_literal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, ".env"), "CALL=\"paramsJoin(a, b)\"\n")
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), `{
+		value: env(CALL)
+		escaped: \paramsJoin(c, d)
+		items: [
+			{
+				name: env(CALL)
+			}
+		]
+	}`)
+
+	for _, index := range []bool{true, false} {
+		opts := []conf.Option{
+			conf.WithConfigDirs(configDir),
+			conf.WithEnvDirs(configDir),
+			conf.WithProcessEnv(false),
+			conf.WithEvaluators(new(testEvalFunction)),
+			conf.WithEagerEvaluation(),
+		}
+		if !index {
+			opts = append(opts, conf.WithoutKeyIndex())
+		}
+		configure, err := conf.Load(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		checkString(configure, "app.value", "paramsJoin(a, b)", t)
+		checkString(configure, "app.escaped", "paramsJoin(c, d)", t)
+		checkString(configure, "app.items[-1].name", "paramsJoin(a, b)", t)
+		if value := configure.GetMap("app", nil)["value"]; value != "paramsJoin(a, b)" {
+			t.Errorf("Expected the result in GetMap as it is, got %v", value)
+		}
+		if escaped := configure.ConfigsMap()["app"].(map[string]interface{})["escaped"]; escaped != "paramsJoin(c, d)" {
+			t.Errorf("Expected the unescaped value in ConfigsMap, got %v", escaped)
+		}
+		if names, err := configure.Query(`app.items[?name=="paramsJoin(a, b)"].name`); err != nil || len(names) != 1 || names[0] != "paramsJoin(a, b)" {
+			t.Errorf("Expected the result in queries as it is, got %v %v", names, err)
+		}
+	}
+}
+
+func TestConfig_EagerRegisterEvaluator(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_eager_register")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), `{
+		joined: paramsJoin(a, b)
+		counted: counter()
+	}`)
+	if err = os.Mkdir(filepath.Join(configDir, conf.SchemaDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeConfigFile(t, filepath.Join(configDir, conf.SchemaDir, "app.schema.json"), `{
+		"properties": { "counted": { "type": "string" } }
+	}`)
+
+	configure, err := conf.Load(conf.WithConfigDirs(configDir), conf.WithEagerEvaluation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkString(configure, "app.joined", "paramsJoin(a, b)", t)
+
+	if err = configure.RegisterEvaluator(new(testEvalFunction)); err != nil {
+		t.Fatal(err)
+	}
+	checkString(configure, "app.joined", "a:b", t)
+	if joined := configure.ConfigsMap()["app"].(map[string]interface{})["joined"]; joined != "a:b" {
+		t.Errorf("Expected registering to evaluate calls eagerly, got %v", joined)
+	}
+
+	err = configure.RegisterEvaluator(new(countingEvaluator))
+	if _, ok := err.(conf.ValidationErrors); !ok {
+		t.Errorf("Expected registering to validate the evaluated configs, got %v", err)
+	}
+	checkString(configure, "app.counted", "counter()", t)
+
+	if !configure.UnregisterEvaluator("paramsJoin") {
+		t.Fatal("Expected paramsJoin to be unregistered")
+	}
+	checkString(configure, "app.joined", "paramsJoin(a, b)", t)
+	if joined := configure.ConfigsMap()["app"].(map[string]interface{})["joined"]; joined != "paramsJoin(a, b)" {
+		t.Errorf("Expected unregistering to drop the evaluated values, got %v", joined)
+	}
+}
+
+// timedCountingEvaluator is a cachedCountingEvaluator with another function name
+type timedCountingEvaluator struct {
+	cachedCountingEvaluator
+}
+
+func (c *timedCountingEvaluator) GetFunctionName() string {
+	return "timedCounter"
+}
@@ -1,14 +1,25 @@
 package conf
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
+// envEvaluator reads variables from the env of the snapshot it is bound to,
+// an unbound evaluator is replaced by a bound copy on each load
 type envEvaluator struct {
+	env map[string]string
 }
 
 var _ EvaluatorFunction = (*envEvaluator)(nil)
+var _ EvaluatorExplainer = (*envEvaluator)(nil)
+var _ CachePolicy = (*envEvaluator)(nil)
+var _ CheckedEvaluator = (*envEvaluator)(nil)
 
 func (e *envEvaluator) GetFunctionName() string {
 	return "env"
@@ -16,7 +27,7 @@ func (e *envEvaluator) GetFunctionName() string {
 
 func (e *envEvaluator) Eval(params []string, def interface{}) interface{} {
 	if len(params) > 0 {
-		envVal := os.Getenv(params[0])
+		envVal := e.env[params[0]]
 		if envVal != "" {
 			return envVal
 		}
@@ -29,3 +40,141 @@ func (e *envEvaluator) Eval(params []string, def interface{}) interface{} {
 
 	return def
 }
+
+// CheckedEval reports variables which are not set and have no default
+func (e *envEvaluator) CheckedEval(params []string) (interface{}, error) {
+	if len(params) == 0 || params[0] == "" {
+		return nil, errors.New("env() needs the name of a variable")
+	}
+	value := e.Eval(params, nil)
+	if value == nil {
+		return nil, fmt.Errorf("environment variable %s is not set", params[0])
+	}
+	return value, nil
+}
+
+// CacheTTL caches results until the next reload, the env of a snapshot never changes
+func (e *envEvaluator) CacheTTL() time.Duration {
+	return -1
+}
+
+func (e *envEvaluator) ExplainEval(params []string) (LayerKind, string) {
+	if len(params) > 0 && e.env[params[0]] != "" {
+		return LayerEnv, params[0]
+	}
+	if len(params) == 2 {
+		return LayerDefault, strings.Trim(params[1], " \"'")
+	}
+	return LayerDefault, "evaluator default"
+}
+
+// EnvMode tells New how to handle a missing .env file
+type EnvMode int
+
+const (
+	// EnvOptional ignores a missing .env file
+	EnvOptional EnvMode = iota
+	// EnvRequired fails with *EnvNotFoundError when the .env file is missing
+	EnvRequired
+)
+
+// loadEnv builds the env of a snapshot without touching the process environment:
+// variables of .env, overridden by the process environment when processEnv is set,
+// overridden by .env.<profile> and .env.<profile>.local of the active profile.
+// Files of later envDirs override the same files of the ones before them.
+// The active profile is profile if set, otherwise APP_ENV, otherwise test when running tests
+func loadEnv(envDirs []string, mode EnvMode, processEnv bool, profile string) (*envLoader, string, error) {
+	loader := newEnvLoader(processEnv)
+
+	for _, envDir := range envDirs {
+		path := filepath.Join(envDir, ".env")
+		if err := loader.readFile(path); os.IsNotExist(err) {
+			if mode == EnvRequired {
+				return nil, "", &EnvNotFoundError{File: path}
+			}
+		} else if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if processEnv {
+		for _, variable := range os.Environ() {
+			if index := strings.IndexByte(variable, '='); index > 0 {
+				loader.set(variable[:index], variable[index+1:], "")
+			}
+		}
+	}
+
+	if profile == "" {
+		profile = loader.env[ProfileEnv]
+	}
+	if profile == "" && flag.Lookup("test.v") != nil {
+		profile = TestProfile
+	}
+
+	for _, envDir := range envDirs {
+		// profile env files are optional
+		for _, name := range envFiles(profile)[1:] {
+			if err := loader.readFile(filepath.Join(envDir, name)); err != nil && !os.IsNotExist(err) {
+				return nil, "", err
+			}
+		}
+	}
+
+	return loader, profile, nil
+}
+
+// bindEnv returns a copy of evaluators with the env evaluator, and decrypt evaluators using
+// an EnvKeyProvider, reading from env. A user evaluator registered as env is kept as is
+func bindEnv(evaluators map[string]EvaluatorFunction, env map[string]string) map[string]EvaluatorFunction {
+	bound := make(map[string]EvaluatorFunction, len(evaluators))
+	for name, evaluator := range evaluators {
+		switch typed := evaluator.(type) {
+		case *envEvaluator:
+			evaluator = &envEvaluator{env: env}
+		case *decryptEvaluator:
+			if provider, ok := typed.provider.(*envKeyProvider); ok {
+				evaluator = &decryptEvaluator{provider: &envKeyProvider{name: provider.name, env: env}}
+			}
+		}
+		bound[name] = evaluator
+	}
+	return bound
+}
+
+// LookupEnv returns the value of the environment variable name as seen by the env evaluator
+func (c *Config) LookupEnv(name string) (string, bool) {
+	value, ok := c.load().env[name]
+	return value, ok
+}
+
+// EnvSource returns the env file defining the environment variable name, the file is empty
+// for variables of the process environment and ok is false for unset variables
+func (c *Config) EnvSource(name string) (file string, ok bool) {
+	s := c.load()
+	if _, ok = s.env[name]; !ok {
+		return "", false
+	}
+	return s.envSources[name], true
+}
+
+// Env returns a copy of the environment variables seen by the env evaluator
+func (c *Config) Env() map[string]string {
+	env := c.load().env
+	copied := make(map[string]string, len(env))
+	for name, value := range env {
+		copied[name] = value
+	}
+	return copied
+}
+
+// ExportEnv writes the environment of the config into the process environment with os.Setenv,
+// for code still reading variables with os.Getenv. The env is never exported implicitly
+func (c *Config) ExportEnv() error {
+	for name, value := range c.load().env {
+		if err := os.Setenv(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_IsolatedEnv(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), `{
+		name: env(CONF_TEST_ISOLATED_NAME, "none")
+		stage: env(CONF_TEST_ISOLATED_STAGE)
+	}`)
+
+	newConfig := func(name string) *conf.Config {
+		envDir := filepath.Join(configDir, name)
+		if err := os.Mkdir(envDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		writeConfigFile(t, filepath.Join(envDir, ".env"), "CONF_TEST_ISOLATED_NAME="+name+"\nCONF_TEST_ISOLATED_STAGE=dev\n")
+		writeConfigFile(t, filepath.Join(envDir, ".env.test"), "CONF_TEST_ISOLATED_STAGE=test\n")
+
+		configure, err := conf.New(configDir, envDir, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return configure
+	}
+
+	first := newConfig("first")
+	second := newConfig("second")
+
+	if name := first.GetString("app.name", ""); name != "first" {
+		t.Errorf("Expected first, got %s", name)
+	}
+	if name := second.GetString("app.name", ""); name != "second" {
+		t.Errorf("Expected second, got %s", name)
+	}
+	if stage := second.GetString("app.stage", ""); stage != "test" {
+		t.Errorf("Expected .env.test to override .env, got %s", stage)
+	}
+	if _, ok := os.LookupEnv("CONF_TEST_ISOLATED_NAME"); ok {
+		t.Error("Expected the process environment to be untouched")
+	}
+
+	if value, ok := first.LookupEnv("CONF_TEST_ISOLATED_NAME"); !ok || value != "first" {
+		t.Errorf("Expected first from LookupEnv, got %s", value)
+	}
+	if env := first.Env(); env["CONF_TEST_ISOLATED_STAGE"] != "test" {
+		t.Errorf("Unexpected env %v", env)
+	}
+
+	defer os.Unsetenv("CONF_TEST_ISOLATED_NAME")
+	defer os.Unsetenv("CONF_TEST_ISOLATED_STAGE")
+	if err = first.ExportEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if name := os.Getenv("CONF_TEST_ISOLATED_NAME"); name != "first" {
+		t.Errorf("Expected ExportEnv to set the process environment, got %s", name)
+	}
+}
+
+func TestNew_EnvMode(t *testing.T) {
+	envDir, err := ioutil.TempDir("", "conf_env_mode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(envDir)
+
+	configure, err := conf.New(envDir, envDir, nil)
+	if err != nil || configure == nil {
+		t.Fatalf("Expected a missing optional .env to be ignored, got %v", err)
+	}
+
+	configure, err = conf.New(envDir, envDir, nil, conf.EnvRequired)
+	if notFound, ok := err.(*conf.EnvNotFoundError); !ok || notFound.File != filepath.Join(envDir, ".env") || configure != nil {
+		t.Errorf("Expected EnvNotFoundError, got %v", err)
+	}
+
+	writeConfigFile(t, filepath.Join(envDir, ".env"), "BROKEN='\n")
+	configure, err = conf.New(envDir, envDir, nil, conf.EnvOptional)
+	if _, ok := err.(*conf.ParseError); !ok || configure != nil {
+		t.Errorf("Expected a fatal ParseError for an invalid .env, got %v", err)
+	}
+}
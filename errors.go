@@ -0,0 +1,88 @@
+package conf
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hjson reports syntax errors as "message at line 3,1 >>> sample"
+var hjsonErrorPattern = regexp.MustCompile(`(?s)^(.*) at line (\d+),(\d+) >>> `)
+
+// ParseError is returned when a config file can not be parsed
+type ParseError struct {
+	File string
+	// Line and Column are 1 based, 0 when the parser did not report a position
+	Line    int
+	Column  int
+	Message string
+	// Snippet is the content of the line the error happened at
+	Snippet string
+}
+
+func (e *ParseError) Error() string {
+	if e.Line == 0 {
+		return e.File + ": " + e.Message
+	}
+	return e.File + ":" + strconv.Itoa(e.Line) + ":" + strconv.Itoa(e.Column) + ": " + e.Message +
+		"\n\t" + e.Snippet
+}
+
+// ParseErrors holds the parse errors of several config files
+type ParseErrors []*ParseError
+
+func (e ParseErrors) Error() string {
+	messages := make([]string, len(e))
+	for index, err := range e {
+		messages[index] = err.Error()
+	}
+	return "conf: invalid config files:\n" + strings.Join(messages, "\n")
+}
+
+// EnvNotFoundError is returned when the .env file is missing and EnvRequired is used
+type EnvNotFoundError struct {
+	File string
+}
+
+func (e *EnvNotFoundError) Error() string {
+	return "conf: env file " + e.File + " not found"
+}
+
+// EvaluatorExistsError is returned when registering an evaluator with the name of a registered one
+type EvaluatorExistsError struct {
+	Name string
+}
+
+func (e *EvaluatorExistsError) Error() string {
+	return "conf: evaluator " + e.Name + " is already registered"
+}
+
+// CheckFiles parses every config file inside configDir, including the files of all profiles,
+// and returns the errors of all invalid files as ParseErrors, so they can be fixed in one pass.
+// New stops at the first invalid file
+func CheckFiles(configDir string) error {
+	_, _, err := loadConfigs(configDir, defaultDecoders(), anyProfile, true)
+	return err
+}
+
+func newParseError(file string, content []byte, err error) *ParseError {
+	parseErr := &ParseError{
+		File:    file,
+		Message: err.Error(),
+	}
+
+	match := hjsonErrorPattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return parseErr
+	}
+
+	parseErr.Message = match[1]
+	parseErr.Line, _ = strconv.Atoi(match[2])
+	parseErr.Column, _ = strconv.Atoi(match[3])
+
+	lines := strings.Split(string(content), "\n")
+	if parseErr.Line > 0 && parseErr.Line <= len(lines) {
+		parseErr.Snippet = strings.TrimRight(lines[parseErr.Line-1], "\r")
+	}
+	return parseErr
+}
@@ -0,0 +1,10 @@
+package conf
+
+// ResetDeclarations removes all keys registered with Declare, so tests declaring keys
+// do not leak them into other tests or into repeated runs with -count
+func ResetDeclarations() {
+	declarationsMu.Lock()
+	defer declarationsMu.Unlock()
+
+	declarations = make(map[string]*Declaration)
+}
@@ -0,0 +1,132 @@
+//go:build go1.18
+// +build go1.18
+
+package conf
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Handle follows the value of a single key across reloads.
+// Load is a cheap atomic read, so handles are suited for values read on hot paths
+// like dynamic tuning knobs, instead of looking up the dotted key on every access
+type Handle[T any] struct {
+	config *Config
+	key    string
+	// path is the canonical form of key
+	path       string
+	def        T
+	callbackID uint64
+	value      atomic.Value // *T
+	// refreshMu serializes refreshes, so an older value never replaces a newer one
+	refreshMu sync.Mutex
+
+	mu          sync.Mutex
+	subscribers []chan<- T
+}
+
+// Watch creates a Handle for key of config. Values are converted to T the same way
+// Unmarshal does, def is used when the key does not exist or can not be converted to T
+//
+//	port := conf.Watch[int](config, "app.server.port", 8080)
+//	port.Load() // always the current port
+func Watch[T any](config *Config, key string, def T) *Handle[T] {
+	h := &Handle[T]{
+		config: config,
+		key:    key,
+		def:    def,
+	}
+
+	h.path = config.canonicalKey(key)
+	config.usage.record(h.path)
+
+	// register before the first read so no reload is missed in between, callbacks read
+	// the current snapshot instead of the value they are called with so callbacks of
+	// reloads stored before the first read can not replace it with an older value
+	h.callbackID = config.addChangeCallback(key, func(old interface{}, new interface{}) {
+		h.refresh()
+	})
+	h.refresh()
+
+	return h
+}
+
+// Key returns the config key followed by the handle
+func (h *Handle[T]) Key() string {
+	return h.key
+}
+
+// Load returns the current value of the key
+func (h *Handle[T]) Load() T {
+	return *h.value.Load().(*T)
+}
+
+// Subscribe registers ch to receive new values after each reload changing the key.
+// Sends never block the reload, so values are dropped when ch is not ready to receive;
+// use a buffered channel and Load to read the latest value if that matters
+func (h *Handle[T]) Subscribe(ch chan<- T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.subscribers = append(h.subscribers, ch)
+}
+
+// Unsubscribe stops sending new values to ch
+func (h *Handle[T]) Unsubscribe(ch chan<- T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for index, subscriber := range h.subscribers {
+		if subscriber == ch {
+			h.subscribers = append(h.subscribers[:index:index], h.subscribers[index+1:]...)
+			return
+		}
+	}
+}
+
+// Close stops following reloads, Load keeps returning the last value
+func (h *Handle[T]) Close() {
+	h.config.removeChangeCallback(h.callbackID)
+
+	h.mu.Lock()
+	h.subscribers = nil
+	h.mu.Unlock()
+}
+
+// refresh stores the value of the current snapshot and sends it to subscribers
+func (h *Handle[T]) refresh() {
+	h.refreshMu.Lock()
+	defer h.refreshMu.Unlock()
+
+	value := h.convert(resolvePrefix(h.config.load(), h.path))
+	h.set(value)
+
+	h.mu.Lock()
+	subscribers := h.subscribers
+	h.mu.Unlock()
+
+	for _, subscriber := range subscribers {
+		select {
+		case subscriber <- value:
+		default:
+		}
+	}
+}
+
+func (h *Handle[T]) set(value T) {
+	h.value.Store(&value)
+}
+
+func (h *Handle[T]) convert(raw interface{}) T {
+	if raw == nil {
+		return h.def
+	}
+
+	var value T
+	if err := decodeValue(raw, reflect.ValueOf(&value).Elem()); err != nil {
+		return h.def
+	}
+	return value
+}
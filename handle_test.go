@@ -0,0 +1,129 @@
+//go:build go1.18
+// +build go1.18
+
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_handle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	appFile := filepath.Join(configDir, "app.hjson")
+	writeConfigFile(t, appFile, "{ server: { port: 8080, timeout: \"2s\", hosts: [\"a\"] } }")
+
+	configure, err := conf.New(configDir, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port := conf.Watch[int](configure, "app.server.port", 1)
+	timeout := conf.Watch[time.Duration](configure, "app.server.timeout", time.Second)
+	hosts := conf.Watch[[]string](configure, "app.server.hosts", nil)
+	missing := conf.Watch[string](configure, "app.server.missing", "default")
+	defer port.Close()
+
+	if port.Load() != 8080 || timeout.Load() != 2*time.Second || len(hosts.Load()) != 1 || missing.Load() != "default" {
+		t.Errorf("Unexpected initial values: %d %v %v %s", port.Load(), timeout.Load(), hosts.Load(), missing.Load())
+	}
+
+	updates := make(chan int, 1)
+	port.Subscribe(updates)
+
+	writeConfigFile(t, appFile, "{ server: { port: 9090, timeout: \"2s\", hosts: [\"a\", \"b\"] } }")
+	if err = configure.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case update := <-updates:
+		if update != 9090 {
+			t.Errorf("Expected port 9090 in subscription, got %d", update)
+		}
+	default:
+		t.Error("Subscriber was not notified about the new port")
+	}
+
+	if port.Load() != 9090 || len(hosts.Load()) != 2 {
+		t.Errorf("Handles did not follow the reload: %d %v", port.Load(), hosts.Load())
+	}
+
+	port.Close()
+	writeConfigFile(t, appFile, "{ server: { port: \"not a number\" } }")
+	if err = configure.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if port.Load() != 9090 {
+		t.Errorf("Closed handle should keep its last value, found %d", port.Load())
+	}
+	if timeout.Load() != time.Second {
+		t.Errorf("Expected default timeout after the key was removed, found %v", timeout.Load())
+	}
+}
+
+// blockingEvaluator signals reading and blocks until release is closed on its first call
+type blockingEvaluator struct {
+	reading chan struct{}
+	release chan struct{}
+	calls   int32
+}
+
+func (b *blockingEvaluator) GetFunctionName() string {
+	return "blocking"
+}
+func (b *blockingEvaluator) Eval(params []string, def interface{}) interface{} {
+	if atomic.AddInt32(&b.calls, 1) == 1 {
+		close(b.reading)
+		<-b.release
+	}
+	return 1.0
+}
+
+func TestWatch_ChangeDuringFirstRead(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_handle_race")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), "port: blocking()")
+
+	evaluator := &blockingEvaluator{reading: make(chan struct{}), release: make(chan struct{})}
+	configure, err := conf.New(configDir, "", []conf.EvaluatorFunction{evaluator})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handles := make(chan *conf.Handle[int])
+	go func() {
+		handles <- conf.Watch[int](configure, "app.port", -1)
+	}()
+	<-evaluator.reading
+
+	// the change is stored while the handle reads its first value
+	set := make(chan error)
+	go func() {
+		set <- configure.Set("app.port", 2)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(evaluator.release)
+
+	handle := <-handles
+	if err = <-set; err != nil {
+		t.Fatal(err)
+	}
+	if port := handle.Load(); port != 2 {
+		t.Errorf("Expected the handle to keep the newer value 2, got %d", port)
+	}
+}
@@ -0,0 +1,84 @@
+package conf
+
+// flatNode is the value of a key in the flattened index of a snapshot
+type flatNode struct {
+	value interface{}
+	// resolved is the evaluated value of a string, valid when cached is set
+	resolved interface{}
+	cached   bool
+}
+
+// buildFlatIndex maps the canonical key of every object, array and value inside configs
+// to its value, so reading a key is a single map lookup. Plain strings and calls of
+// evaluators cached until the next reload (see CachePolicy) which do not fall back to the
+// default given to the getter are evaluated once here, other evaluators on each read
+func buildFlatIndex(s *snapshot) map[string]flatNode {
+	index := make(map[string]flatNode)
+
+	var walk func(key string, value interface{})
+	walk = func(key string, value interface{}) {
+		node := flatNode{value: value}
+		switch typed := value.(type) {
+		case map[string]interface{}:
+			for name, item := range typed {
+				walk(joinKey(key, name), item)
+			}
+		case []interface{}:
+			for position, item := range typed {
+				walk(indexKey(key, position), item)
+			}
+		case string:
+			if s.literals[key] {
+				node.resolved, node.cached = typed, true
+				break
+			}
+			node.resolved, node.cached = cacheableValue(s, typed)
+		}
+		if key != "" {
+			index[key] = node
+		}
+	}
+	walk("", s.configs)
+
+	return index
+}
+
+// cacheableValue returns the value of content to keep in the index, ok is false for calls
+// which have to be evaluated on each read
+func cacheableValue(s *snapshot, content string) (interface{}, bool) {
+	methodName, params, ok := parseCall(s, content)
+	if !ok {
+		return unescapeCall(s, content), true
+	}
+	if policy, ok := s.evaluators[methodName].(CachePolicy); !ok || policy.CacheTTL() >= 0 {
+		return nil, false
+	}
+
+	value, err := evalChecked(methodName, s.evaluators[methodName], params)
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// getIndexed reads key from the flattened index, ok is false when key is not a
+// canonical key of the index and get has to walk the configs
+func getIndexed(s *snapshot, key string, def interface{}) (interface{}, bool) {
+	node, ok := s.flat[key]
+	if !ok {
+		return nil, false
+	}
+	if node.cached {
+		return node.resolved, true
+	}
+	if node.value == nil {
+		return def, true
+	}
+	switch typed := node.value.(type) {
+	case string:
+		return evalStringValue(s, typed, def), true
+	case map[string]interface{}, []interface{}:
+		return resolveValue(s, key, typed), true
+	}
+	return node.value, true
+}
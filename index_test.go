@@ -0,0 +1,179 @@
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const indexTestConfig = `{
+	server: {
+		host: env(CONF_TEST_INDEX_HOST, "localhost")
+		port: 8080
+		tls: { enabled: true, cert: "/etc/cert.pem" }
+	}
+	upstreams: [
+		{ name: "a", weight: 1 }
+		{ name: "b", weight: 2 }
+	]
+	evaluated: paramsJoin(1,2,3)
+}`
+
+func newIndexTestConfigs(t testing.TB) (string, *conf.Config, *conf.Config) {
+	configDir, err := ioutil.TempDir("", "conf_index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), indexTestConfig)
+
+	indexed, err := conf.Load(conf.WithConfigDirs(configDir), conf.WithEvaluators(new(testEvalFunction)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	walked, err := conf.Load(conf.WithConfigDirs(configDir), conf.WithEvaluators(new(testEvalFunction)), conf.WithoutKeyIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return configDir, indexed, walked
+}
+
+func TestConfig_KeyIndex(t *testing.T) {
+	configDir, indexed, walked := newIndexTestConfigs(t)
+	defer os.RemoveAll(configDir)
+
+	keys := append(indexed.Keys(), "app.server", "app.upstreams[1]", "app.upstreams[-1].name", "app.missing")
+	for _, key := range keys {
+		if expected, actual := walked.Get(key, "def"), indexed.Get(key, "def"); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("Expected %v for %s, got %v", expected, key, actual)
+		}
+	}
+	checkString(indexed, "app.server.host", "localhost", t)
+	checkString(indexed, "app.evaluated", "1:2:3", t)
+}
+
+// baselineGet is the lookup of the implementation before the key index, kept as the
+// baseline of the benchmarks: it splits the key on every read and walks the configs recursively
+func baselineGet(configure *conf.Config, key string, def interface{}) interface{} {
+	keys := strings.Split(key, ".")
+	if len(keys) < 1 {
+		return def
+	}
+
+	return baselineIterateForKey(configure, &keys, configure.ConfigsMap(), def)
+}
+
+func baselineIterateForKey(configure *conf.Config, keys *[]string, configs map[string]interface{}, def interface{}) interface{} {
+	if len(*keys) < 1 {
+		return def
+	}
+
+	key := (*keys)[0]
+	isArray := false
+	var arrayIndex int
+	var err error
+	if strings.Contains(key, "[") && strings.Contains(key, "]") {
+		indexStart := strings.Index(key, "[")
+		indexEnd := strings.Index(key, "]")
+		keyName := key[:indexStart]
+
+		isArray = true
+		arrayIndex, err = strconv.Atoi(key[indexStart+1 : indexEnd])
+		if err != nil {
+			return def
+		}
+
+		key = keyName
+	}
+
+	if len(*keys) == 1 {
+		if configs[key] != nil {
+			if isArray {
+				return configs[key].([]interface{})[arrayIndex]
+			}
+
+			if reflect.TypeOf(configs[key]).Kind() == reflect.String {
+				return baselineEvalStringValue(configure, configs[key].(string), def)
+			}
+			return configs[key]
+		}
+
+		return def
+	}
+
+	if configs[key] != nil {
+		if isArray {
+			newKeys := (*keys)[1:]
+			arr := configs[key].([]interface{})
+			return baselineIterateForKey(configure, &newKeys, arr[arrayIndex].(map[string]interface{}), def)
+		}
+
+		newKeys := (*keys)[1:]
+		return baselineIterateForKey(configure, &newKeys, configs[key].(map[string]interface{}), def)
+	}
+
+	return def
+}
+
+func baselineEvalStringValue(configure *conf.Config, content string, def interface{}) interface{} {
+	evalStartIndex := strings.Index(content, "(")
+	evalEndIndex := strings.Index(content, ")")
+	if evalStartIndex > 0 && evalEndIndex > 0 {
+		methodName := strings.Trim(content[:evalStartIndex], "\"\t' ")
+		if evaluator := configure.EvaluatorFunctionsMap()[methodName]; evaluator != nil {
+			evalParams := strings.Split(content[evalStartIndex+1:evalEndIndex], ",")
+			var evalParamsSanitized []string
+			for _, param := range evalParams {
+				evalParamsSanitized = append(evalParamsSanitized, strings.Trim(param, "\"\t' "))
+			}
+
+			return evaluator.Eval(evalParamsSanitized, def)
+		}
+	}
+	return content
+}
+
+// benchmarkGet compares reading key through the key index and by walking the configs
+// with the baseline lookup
+func benchmarkGet(b *testing.B, key string) {
+	configDir, indexed, walked := newIndexTestConfigs(b)
+	defer os.RemoveAll(configDir)
+
+	if expected, actual := baselineGet(walked, key, nil), indexed.Get(key, nil); !reflect.DeepEqual(expected, actual) {
+		b.Fatalf("Expected %v for %s, got %v", expected, key, actual)
+	}
+
+	lookups := []struct {
+		name string
+		get  func() interface{}
+	}{
+		{"baseline", func() interface{} { return baselineGet(walked, key, nil) }},
+		{"walked", func() interface{} { return walked.Get(key, nil) }},
+		{"indexed", func() interface{} { return indexed.Get(key, nil) }},
+	}
+	for _, lookup := range lookups {
+		lookup := lookup
+		b.Run(lookup.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				lookup.get()
+			}
+		})
+	}
+}
+
+func BenchmarkConfig_GetString(b *testing.B) {
+	benchmarkGet(b, "app.server.tls.cert")
+}
+
+func BenchmarkConfig_GetEnv(b *testing.B) {
+	benchmarkGet(b, "app.server.host")
+}
+
+func BenchmarkConfig_GetArrayElement(b *testing.B) {
+	benchmarkGet(b, "app.upstreams[1].weight")
+}
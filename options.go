@@ -0,0 +1,237 @@
+package conf
+
+import (
+	"errors"
+	"time"
+)
+
+// Decoder parses the content of a config file. Values must use the types produced by
+// encoding/json: map[string]interface{}, []interface{}, string, float64, bool and nil
+type Decoder func(content []byte) (map[string]interface{}, error)
+
+// Option configures Load
+type Option func(*options)
+
+type options struct {
+	configDirs []string
+	envDirs    []string
+	decoders   map[string]Decoder
+	evaluators []EvaluatorFunction
+	profile    string
+	envMode    EnvMode
+	processEnv bool
+	strict     bool
+	delimiter  string
+	// caseInsensitive matches keys regardless of their case
+	caseInsensitive bool
+	noIndex         bool
+	eager           bool
+	lazyEvaluators  []string
+	evalPrefix      string
+
+	watch         bool
+	watchInterval time.Duration
+	watchNotify   bool
+}
+
+// WithConfigDirs adds config directories, configs of later directories are merged
+// over the ones before them. Directories which do not exist are skipped, so optional
+// directories like /etc/app can be listed
+func WithConfigDirs(dirs ...string) Option {
+	return func(o *options) {
+		o.configDirs = append(o.configDirs, dirs...)
+	}
+}
+
+// WithEnvDirs adds directories holding .env files, variables of later directories
+// override the ones before them
+func WithEnvDirs(dirs ...string) Option {
+	return func(o *options) {
+		o.envDirs = append(o.envDirs, dirs...)
+	}
+}
+
+// WithDecoder parses config files with extension (like ".yaml") using decoder,
+// .hjson and .json files are decoded as hjson unless replaced
+func WithDecoder(extension string, decoder Decoder) Option {
+	return func(o *options) {
+		o.decoders[extension] = decoder
+	}
+}
+
+// WithEvaluators registers evaluators. Load fails with *EvaluatorExistsError when two evaluators,
+// or an evaluator and the built-in env evaluator, have the same function name. To replace the
+// env evaluator call UnregisterEvaluator("env") and RegisterEvaluator after loading
+func WithEvaluators(evaluators ...EvaluatorFunction) Option {
+	return func(o *options) {
+		o.evaluators = append(o.evaluators, evaluators...)
+	}
+}
+
+// WithProfile selects the active profile instead of APP_ENV
+func WithProfile(profile string) Option {
+	return func(o *options) {
+		o.profile = profile
+	}
+}
+
+// WithEnvMode tells Load how to handle missing .env files, EnvOptional by default
+func WithEnvMode(mode EnvMode) Option {
+	return func(o *options) {
+		o.envMode = mode
+	}
+}
+
+// WithProcessEnv makes the process environment visible to the env evaluator, enabled by default
+func WithProcessEnv(enabled bool) Option {
+	return func(o *options) {
+		o.processEnv = enabled
+	}
+}
+
+// WithStrict makes Load fail when required keys are missing (see Check) or when configs
+// have unknown keys (see CheckUnknownKeys). Keys must be declared before calling Load
+func WithStrict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// WithWatcher starts the watcher after loading, see StartWatcher
+func WithWatcher(interval time.Duration, notify bool) Option {
+	return func(o *options) {
+		o.watch = true
+		o.watchInterval = interval
+		o.watchNotify = notify
+	}
+}
+
+// WithKeyDelimiter separates the parts of keys with delimiter instead of a dot,
+// so app/server/port can be used instead of app.server.port
+func WithKeyDelimiter(delimiter string) Option {
+	return func(o *options) {
+		o.delimiter = delimiter
+	}
+}
+
+// WithCaseInsensitiveKeys matches keys regardless of their case, so Server.Port reads
+// server.port. Loading fails with ValidationErrors when keys of an object differ only by case
+func WithCaseInsensitiveKeys() Option {
+	return func(o *options) {
+		o.caseInsensitive = true
+	}
+}
+
+// WithoutKeyIndex disables the flattened index of all keys built on each load, saving
+// its memory at the cost of walking the configs on each read
+func WithoutKeyIndex() Option {
+	return func(o *options) {
+		o.noIndex = true
+	}
+}
+
+// WithEagerEvaluation evaluates all evaluator calls once while loading, so getters, GetMap and
+// ConfigsMap return their results without calling evaluators. Loading fails with ValidationErrors
+// when an evaluator has no value (see CheckedEvaluator). Calls of the lazy evaluators and of
+// evaluators whose results expire (see CachePolicy) are still evaluated on each read
+func WithEagerEvaluation(lazy ...string) Option {
+	return func(o *options) {
+		o.eager = true
+		o.lazyEvaluators = append(o.lazyEvaluators, lazy...)
+	}
+}
+
+// WithEvaluatorPrefix evaluates only calls whose name starts with prefix, so with "$"
+// $env(HOST) is evaluated and env(HOST) is a literal string
+func WithEvaluatorPrefix(prefix string) Option {
+	return func(o *options) {
+		o.evalPrefix = prefix
+	}
+}
+
+// Load creates a config from options. At least one config directory is required
+//
+//	config, err := conf.Load(
+//		conf.WithConfigDirs("/etc/app", "/path/to/configs"),
+//		conf.WithEnvDirs("/path/to/envs"),
+//		conf.WithEvaluators(new(MyEvaluator)),
+//		conf.WithStrict(),
+//	)
+func Load(opts ...Option) (*Config, error) {
+	o := &options{
+		decoders:   defaultDecoders(),
+		processEnv: true,
+		delimiter:  ".",
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if len(o.configDirs) == 0 {
+		return nil, errors.New("conf: no config directory")
+	}
+	if o.delimiter == "" {
+		return nil, errors.New("conf: empty key delimiter")
+	}
+
+	config := &Config{
+		configDirs:      o.configDirs,
+		envDirs:         o.envDirs,
+		decoders:        o.decoders,
+		delimiter:       o.delimiter,
+		caseInsensitive: o.caseInsensitive,
+		noIndex:         o.noIndex,
+		eager:           o.eager,
+		lazyEvaluators:  make(map[string]bool),
+		evalPrefix:      o.evalPrefix,
+		usage:           new(usageTracker),
+		envMode:         o.envMode,
+		processEnv:      o.processEnv,
+		profile:         o.profile,
+	}
+
+	for _, name := range o.lazyEvaluators {
+		config.lazyEvaluators[name] = true
+	}
+
+	envEval := new(envEvaluator)
+	evaluatorsMap := map[string]EvaluatorFunction{
+		envEval.GetFunctionName(): envEval,
+	}
+	for _, evalFunc := range o.evaluators {
+		name := evalFunc.GetFunctionName()
+		if _, ok := evaluatorsMap[name]; ok {
+			return nil, &EvaluatorExistsError{Name: name}
+		}
+		evaluatorsMap[name] = evalFunc
+	}
+
+	s, err := config.loadSnapshot(evaluatorsMap)
+	if s == nil {
+		return nil, err
+	}
+	config.store(s)
+
+	if o.strict {
+		if err = config.Check(); err != nil {
+			return nil, err
+		}
+		if err = config.CheckUnknownKeys(); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.watch {
+		if err = config.StartWatcher(o.watchInterval, o.watchNotify); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+func defaultDecoders() map[string]Decoder {
+	return map[string]Decoder{
+		".hjson": decodeHjson,
+		".json":  decodeHjson,
+	}
+}
@@ -0,0 +1,76 @@
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "conf_load_base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	overrideDir, err := ioutil.TempDir("", "conf_load_override")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(overrideDir)
+
+	writeConfigFile(t, filepath.Join(baseDir, "app.hjson"), `{
+		server: { host: "localhost", port: 8080 }
+		name: env(CONF_TEST_LOAD_NAME, "unnamed")
+	}`)
+	writeConfigFile(t, filepath.Join(overrideDir, "app.hjson"), `{
+		server: { port: 9090 }
+	}`)
+	writeConfigFile(t, filepath.Join(overrideDir, "features.kv"), "cache=on\nsearch=off\n")
+	writeConfigFile(t, filepath.Join(overrideDir, ".env"), "CONF_TEST_LOAD_NAME=loaded\n")
+
+	keyValueDecoder := func(content []byte) (map[string]interface{}, error) {
+		values := make(map[string]interface{})
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+			parts := strings.SplitN(line, "=", 2)
+			values[parts[0]] = parts[1]
+		}
+		return values, nil
+	}
+
+	configure, err := conf.Load(
+		conf.WithConfigDirs(baseDir, overrideDir),
+		conf.WithEnvDirs(overrideDir),
+		conf.WithDecoder(".kv", keyValueDecoder),
+		conf.WithProfile("production"),
+		conf.WithKeyDelimiter("/"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkString(configure, "app/server/host", "localhost", t)
+	if port := configure.GetInt("app/server/port", 0); port != 9090 {
+		t.Errorf("Expected the port of the last config dir, got %d", port)
+	}
+	checkString(configure, "app/name", "loaded", t)
+	checkString(configure, "features/cache", "on", t)
+	if profile := configure.Profile(); profile != "production" {
+		t.Errorf("Expected the production profile, got %s", profile)
+	}
+
+	optional, err := conf.Load(conf.WithConfigDirs(baseDir, filepath.Join(baseDir, "does not exist")))
+	if err != nil {
+		t.Fatalf("Expected missing config dirs to be skipped, got %v", err)
+	}
+	checkString(optional, "app.server.host", "localhost", t)
+
+	if _, err = conf.Load(conf.WithConfigDirs(baseDir), conf.WithStrict()); err == nil {
+		t.Error("Expected strict loading to fail on undeclared keys")
+	}
+	if _, err = conf.Load(); err == nil {
+		t.Error("Expected an error without config dirs")
+	}
+}
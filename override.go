@@ -0,0 +1,227 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// override is a value set with Set, applied over the values of the config files
+type override struct {
+	// key is the canonical key of the value, path its parsed form with absolute indexes
+	key   string
+	path  []pathStep
+	value interface{}
+}
+
+// Set overrides the value of key, creating the objects and arrays along its path.
+// Overrides sit above all config files and survive reloads until removed with Unset.
+// Numbers, slices and maps are converted to the types of decoded files, so getters read
+// them like file values, and strings are evaluated like values of config files.
+// Change callbacks and handles are notified, and the configs are validated against their
+// schemas, keeping the current values and returning the error when they are invalid
+func (c *Config) Set(key string, value interface{}) error {
+	if c.frozen {
+		return errFrozen
+	}
+
+	// deferred first so callbacks run after writeMu is released
+	defer c.dispatchChanges()
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	old := c.load()
+	path, err := c.overridePath(old, key)
+	if err != nil {
+		return err
+	}
+	canonical := formatPath(path, ".")
+
+	overrides := make([]override, 0, len(old.overrides)+1)
+	for _, existing := range old.overrides {
+		if existing.key != canonical {
+			overrides = append(overrides, existing)
+		}
+	}
+	overrides = append(overrides, override{key: canonical, path: path, value: normalizeValue(value)})
+
+	return c.swapOverrides(old, overrides)
+}
+
+// Unset removes the override of key set with Set, so the value of the config files is
+// read again. It returns false if key has no override
+func (c *Config) Unset(key string) bool {
+	if c.frozen {
+		return false
+	}
+
+	// deferred first so callbacks run after writeMu is released
+	defer c.dispatchChanges()
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	old := c.load()
+	path, err := c.overridePath(old, key)
+	if err != nil {
+		return false
+	}
+	canonical := formatPath(path, ".")
+
+	overrides := make([]override, 0, len(old.overrides))
+	for _, existing := range old.overrides {
+		if existing.key != canonical {
+			overrides = append(overrides, existing)
+		}
+	}
+	if len(overrides) == len(old.overrides) {
+		return false
+	}
+
+	// the configs were valid without the override, so this can only fail if files
+	// changed since the last reload; the override is kept in that case
+	return c.swapOverrides(old, overrides) == nil
+}
+
+// lookup returns the value of the key with path steps inside the override,
+// ok is false if the override does not set the key
+func (o override) lookup(steps []pathStep) (interface{}, bool) {
+	if len(steps) < len(o.path) {
+		return nil, false
+	}
+	for position, step := range o.path {
+		if steps[position].kind != step.kind || steps[position].name != step.name || steps[position].index != step.index {
+			return nil, false
+		}
+	}
+	return lookupSteps(o.value, steps[len(o.path):], nil)
+}
+
+// swapOverrides stores a copy of old using overrides, callers must hold writeMu
+func (c *Config) swapOverrides(old *snapshot, overrides []override) error {
+	next := *old
+	next.overrides = overrides
+	next.cache = newEvalCache()
+	if err := c.prepareSnapshot(&next); err != nil {
+		return err
+	}
+
+	c.swap(old, &next)
+	return nil
+}
+
+// overridePath parses key into the path of an override, with the case of existing keys
+// for case insensitive configs and negative indexes of existing arrays made absolute
+func (c *Config) overridePath(s *snapshot, key string) ([]pathStep, error) {
+	delimiter := c.delimiter
+	if delimiter == "" {
+		delimiter = "."
+	}
+	steps, err := parsePath(key, delimiter)
+	if err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return nil, errors.New("conf: empty key")
+	}
+	if isQuery(steps) {
+		return nil, fmt.Errorf("conf: can not set %s, wildcards and filters select several values", key)
+	}
+
+	steps = s.foldSteps(steps)
+	var value interface{} = s.raw
+	for position, step := range steps {
+		switch step.kind {
+		case stepName:
+			object, _ := value.(map[string]interface{})
+			value = object[step.name]
+		case stepIndex:
+			array, _ := value.([]interface{})
+			index, ok := arrayIndex(array, step.index)
+			if index < 0 {
+				return nil, fmt.Errorf("conf: can not set %s, index %d is out of range", key, step.index)
+			}
+			steps[position].index = index
+			value = nil
+			if ok {
+				value = array[index]
+			}
+		}
+	}
+	return steps, nil
+}
+
+// applyOverrides returns a copy of configs with overrides set, objects and arrays
+// along the paths of overrides are copied so configs is never modified
+func applyOverrides(configs map[string]interface{}, overrides []override) map[string]interface{} {
+	for _, o := range overrides {
+		configs = setPath(configs, o.path, o.value).(map[string]interface{})
+	}
+	return configs
+}
+
+// setPath returns a copy of current with value at path. Missing objects and arrays are
+// created, and values which are not objects or arrays along the path are replaced
+func setPath(current interface{}, path []pathStep, value interface{}) interface{} {
+	if len(path) == 0 {
+		return value
+	}
+
+	step := path[0]
+	if step.kind == stepIndex {
+		array, _ := current.([]interface{})
+		size := len(array)
+		if step.index >= size {
+			size = step.index + 1
+		}
+		copied := make([]interface{}, size)
+		copy(copied, array)
+		copied[step.index] = setPath(copied[step.index], path[1:], value)
+		return copied
+	}
+
+	object, _ := current.(map[string]interface{})
+	copied := make(map[string]interface{}, len(object)+1)
+	for name, item := range object {
+		copied[name] = item
+	}
+	copied[step.name] = setPath(object[step.name], path[1:], value)
+	return copied
+}
+
+// normalizeValue converts numbers to float64, slices to []interface{} and maps with
+// string keys to map[string]interface{}, the types produced by decoders
+func normalizeValue(value interface{}) interface{} {
+	switch value.(type) {
+	case nil, string, bool, float64:
+		return value
+	}
+
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(reflected.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(reflected.Uint())
+	case reflect.Float32:
+		return reflected.Float()
+	case reflect.Slice, reflect.Array:
+		if reflected.Kind() == reflect.Slice && reflected.IsNil() {
+			return nil
+		}
+		normalized := make([]interface{}, reflected.Len())
+		for index := range normalized {
+			normalized[index] = normalizeValue(reflected.Index(index).Interface())
+		}
+		return normalized
+	case reflect.Map:
+		if reflected.Type().Key().Kind() != reflect.String {
+			return value
+		}
+		normalized := make(map[string]interface{}, reflected.Len())
+		for _, name := range reflected.MapKeys() {
+			normalized[name.String()] = normalizeValue(reflected.MapIndex(name).Interface())
+		}
+		return normalized
+	}
+	return value
+}
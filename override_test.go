@@ -0,0 +1,112 @@
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_Set(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_set")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	if err = os.Mkdir(filepath.Join(configDir, conf.SchemaDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeConfigFile(t, filepath.Join(configDir, conf.SchemaDir, "app.schema.json"), `{
+		"type": "object",
+		"properties": { "server": { "type": "object", "properties": { "port": { "type": "integer" } } } }
+	}`)
+	appFile := filepath.Join(configDir, "app.hjson")
+	writeConfigFile(t, appFile, `{
+		server: { port: 8080, hosts: ["a", "b"] }
+	}`)
+
+	configure, err := conf.Load(conf.WithConfigDirs(configDir), conf.WithEvaluators(new(testEvalFunction)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan interface{}, 10)
+	configure.OnChange("app.server.port", func(old interface{}, new interface{}) {
+		changes <- new
+	})
+
+	if err = configure.Set("app.server.port", 9090); err != nil {
+		t.Fatal(err)
+	}
+	if value := configure.GetInt("app.server.port", 0); value != 9090 {
+		t.Errorf("Expected 9090, got %d", value)
+	}
+	if change := <-changes; change != 9090.0 {
+		t.Errorf("Expected a change to 9090, got %v", change)
+	}
+
+	if err = configure.Set("app.server.hosts[-1]", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if err = configure.Set("app.tls.certs[1].name", "paramsJoin(x, y)"); err != nil {
+		t.Fatal(err)
+	}
+	if err = configure.Set("app.limits", map[string]int{"rps": 10}); err != nil {
+		t.Fatal(err)
+	}
+	checkString(configure, "app.server.hosts[1]", "c", t)
+	checkString(configure, "app.tls.certs[1].name", "x:y", t)
+	if certs := configure.Get("app.tls.certs", nil).([]interface{}); len(certs) != 2 || certs[0] != nil {
+		t.Errorf("Expected the array to be created, got %v", certs)
+	}
+	if rps := configure.GetInt("app.limits.rps", 0); rps != 10 {
+		t.Errorf("Expected 10, got %d", rps)
+	}
+
+	if err = configure.Set("app.server.port", "not a number"); err == nil {
+		t.Error("Expected a schema error")
+	}
+	if err = configure.Set("app.server.hosts[*]", "d"); err == nil {
+		t.Error("Expected an error setting a wildcard")
+	}
+
+	explanation := configure.Explain("app.server.port")
+	if layers := explanation.Layers; len(layers) != 2 || !layers[0].Overridden || layers[1].Kind != conf.LayerOverride {
+		t.Errorf("Expected an override layer, got %s", explanation)
+	}
+
+	writeConfigFile(t, appFile, `{
+		server: { port: 7070, hosts: ["a", "b"], timeout: 5 }
+	}`)
+	if err = configure.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if value := configure.GetInt("app.server.port", 0); value != 9090 {
+		t.Errorf("Expected the override to survive the reload, got %d", value)
+	}
+	if timeout := configure.GetInt("app.server.timeout", 0); timeout != 5 {
+		t.Errorf("Expected reloaded values next to overrides, got %d", timeout)
+	}
+
+	if !configure.Unset("app.server.port") {
+		t.Error("Expected the override to be removed")
+	}
+	if configure.Unset("app.server.port") {
+		t.Error("Expected no override to remove")
+	}
+	if value := configure.GetInt("app.server.port", 0); value != 7070 {
+		t.Errorf("Expected the file value after Unset, got %d", value)
+	}
+	if change := <-changes; change != 7070.0 {
+		t.Errorf("Expected a change to 7070 after Unset, got %v", change)
+	}
+	if !configure.Unset("app.server.hosts[1]") {
+		t.Error("Expected the override of the array item to be removed")
+	}
+	checkString(configure, "app.server.hosts[1]", "b", t)
+
+	if err = configure.Snapshot().Set("app.server.port", 1); err == nil {
+		t.Error("Expected an error setting values of a snapshot")
+	}
+}
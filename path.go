@@ -0,0 +1,357 @@
+package conf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// stepKind tells how a step of a path selects values
+type stepKind int
+
+const (
+	// stepName selects the value of an object key
+	stepName stepKind = iota
+	// stepIndex selects an array element, negative indexes count from the end
+	stepIndex
+	// stepWildcard selects all elements of an array or all values of an object
+	stepWildcard
+	// stepFilter selects the elements of an array matching a condition
+	stepFilter
+)
+
+// pathStep is a single step of a key path
+type pathStep struct {
+	kind   stepKind
+	name   string
+	index  int
+	filter *pathFilter
+}
+
+// parsePath splits key into the steps of its path. Parts are separated by delimiter,
+// array indexes are written as [n] and keys containing the delimiter or brackets are
+// quoted, either as a part ("example.com") or inside brackets (["example.com"]).
+// [*] or a * part select every element, [?condition] selects matching array elements
+//
+//	hosts."example.com".port
+//	hosts["example.com"].port
+//	matrix[0][-1]
+//	servers[*].port
+//	servers[?role=="primary"].port
+func parsePath(key string, delimiter string) ([]pathStep, error) {
+	steps := make([]pathStep, 0, strings.Count(key, delimiter)+strings.Count(key, "[")+1)
+	position := 0
+	// expectName is true at the start of the key and after each delimiter
+	expectName := true
+	for position < len(key) {
+		switch {
+		case key[position] == '[':
+			if expectName && position > 0 {
+				return nil, pathError(key, position, "expected a key before [")
+			}
+			step, next, err := parseBracket(key, position+1)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+			position = next
+			expectName = false
+
+		case !expectName:
+			if !strings.HasPrefix(key[position:], delimiter) {
+				return nil, pathError(key, position, "expected "+delimiter+" or [")
+			}
+			position += len(delimiter)
+			expectName = true
+			if position == len(key) {
+				return nil, pathError(key, position, "expected a key after "+delimiter)
+			}
+
+		case key[position] == '"':
+			name, next, err := parseQuoted(key, position)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, pathStep{name: name})
+			position = next
+			expectName = false
+
+		default:
+			end := len(key)
+			if next := strings.Index(key[position:], delimiter); next >= 0 {
+				end = position + next
+			}
+			if next := strings.IndexByte(key[position:end], '['); next >= 0 {
+				end = position + next
+			}
+			if name := key[position:end]; name == "*" {
+				steps = append(steps, pathStep{kind: stepWildcard})
+			} else {
+				steps = append(steps, pathStep{name: name})
+			}
+			position = end
+			expectName = false
+		}
+	}
+	return steps, nil
+}
+
+// parseBracket parses the content of brackets starting at position, after the [.
+// It returns the step and the position after the closing ]
+func parseBracket(key string, position int) (pathStep, int, error) {
+	var step pathStep
+	switch {
+	case position < len(key) && (key[position] == '"' || key[position] == '\''):
+		name, next, err := parseQuoted(key, position)
+		if err != nil {
+			return step, 0, err
+		}
+		step, position = pathStep{name: name}, next
+
+	case position < len(key) && key[position] == '?':
+		end := closingBracket(key, position)
+		if end < 0 {
+			return step, 0, pathError(key, position, "unterminated [")
+		}
+		filter, err := parseFilter(key[position+1 : end])
+		if err != nil {
+			return step, 0, pathError(key, position, err.Error())
+		}
+		step, position = pathStep{kind: stepFilter, filter: filter}, end
+
+	default:
+		end := strings.IndexByte(key[position:], ']')
+		if end < 0 {
+			return step, 0, pathError(key, position, "unterminated [")
+		}
+		content := strings.TrimSpace(key[position : position+end])
+		if content == "*" {
+			step = pathStep{kind: stepWildcard}
+		} else {
+			index, err := strconv.Atoi(content)
+			if err != nil {
+				return step, 0, pathError(key, position, "invalid array index")
+			}
+			step = pathStep{kind: stepIndex, index: index}
+		}
+		position += end
+	}
+
+	if position >= len(key) || key[position] != ']' {
+		return step, 0, pathError(key, position, "expected ]")
+	}
+	return step, position + 1, nil
+}
+
+// closingBracket returns the position of the ] closing the brackets containing position,
+// skipping quoted strings and nested brackets
+func closingBracket(key string, position int) int {
+	var quote byte
+	depth := 0
+	for ; position < len(key); position++ {
+		switch char := key[position]; {
+		case quote != 0 && char == '\\':
+			position++
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '"' || char == '\'':
+			quote = char
+		case char == '[':
+			depth++
+		case char == ']':
+			if depth == 0 {
+				return position
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// parseQuoted parses the quoted string starting at position of key, backslash escapes
+// the next character. It returns the unquoted string and the position after the closing quote
+func parseQuoted(key string, position int) (string, int, error) {
+	quote := key[position]
+	var builder strings.Builder
+	for index := position + 1; index < len(key); index++ {
+		switch key[index] {
+		case '\\':
+			index++
+			if index < len(key) {
+				builder.WriteByte(key[index])
+			}
+		case quote:
+			return builder.String(), index + 1, nil
+		default:
+			builder.WriteByte(key[index])
+		}
+	}
+	return "", 0, pathError(key, position, "unterminated quoted key")
+}
+
+func pathError(key string, position int, message string) error {
+	return fmt.Errorf("conf: invalid key %q at %d: %s", key, position, message)
+}
+
+// formatPath writes steps as a key separated by delimiter, quoting the keys which
+// can not be written as they are
+func formatPath(steps []pathStep, delimiter string) string {
+	size := 0
+	for _, step := range steps {
+		size += len(step.name) + len(delimiter) + 2
+	}
+	var builder strings.Builder
+	builder.Grow(size)
+	for index, step := range steps {
+		switch step.kind {
+		case stepIndex:
+			builder.WriteString("[" + strconv.Itoa(step.index) + "]")
+		case stepWildcard:
+			builder.WriteString("[*]")
+		case stepFilter:
+			builder.WriteString("[?" + step.filter.raw + "]")
+		default:
+			if index > 0 {
+				builder.WriteString(delimiter)
+			}
+			builder.WriteString(formatName(step.name, delimiter))
+		}
+	}
+	return builder.String()
+}
+
+func formatName(name string, delimiter string) string {
+	if name != "" && name != "*" && !strings.Contains(name, delimiter) && !needsQuotes(name) {
+		return name
+	}
+	escaped := strings.Replace(name, "\\", "\\\\", -1)
+	escaped = strings.Replace(escaped, "\"", "\\\"", -1)
+	return "\"" + escaped + "\""
+}
+
+// needsQuotes reports if name contains brackets, quotes or backslashes
+func needsQuotes(name string) bool {
+	for index := 0; index < len(name); index++ {
+		switch name[index] {
+		case '[', ']', '"', '\\':
+			return true
+		}
+	}
+	return false
+}
+
+// isQuery reports if steps can select more than one value
+func isQuery(steps []pathStep) bool {
+	for _, step := range steps {
+		if step.kind == stepWildcard || step.kind == stepFilter {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupSteps returns the raw value at the path of steps inside value,
+// paths selecting several values are never found. Keys are matched case
+// insensitively when keys is not nil
+func lookupSteps(value interface{}, steps []pathStep, keys keyIndex) (interface{}, bool) {
+	for _, step := range steps {
+		switch step.kind {
+		case stepIndex:
+			array, ok := value.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			index, ok := arrayIndex(array, step.index)
+			if !ok {
+				return nil, false
+			}
+			value = array[index]
+
+		case stepName:
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if _, value, ok = keys.find(object, step.name); !ok {
+				return nil, false
+			}
+
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// arrayIndex converts index of array to a positive index, negative indexes count from the end
+func arrayIndex(array []interface{}, index int) (int, bool) {
+	if index < 0 {
+		index += len(array)
+	}
+	return index, index >= 0 && index < len(array)
+}
+
+// canonicalKey converts a key written with the key delimiter of the config to the dotted
+// form used internally, with the case of the keys in the configs for case insensitive configs.
+// Invalid keys are returned as they are
+func (c *Config) canonicalKey(key string) string {
+	key, _ = c.resolveKey(c.load(), key)
+	return key
+}
+
+// resolveKey returns the canonical form of key in s together with its parsed steps, so
+// reading the key does not parse it again. Steps are nil for keys of the flattened index
+// and for invalid keys
+func (c *Config) resolveKey(s *snapshot, key string) (string, []pathStep) {
+	delimiter := c.delimiter
+	if delimiter == "" {
+		delimiter = "."
+	}
+	if _, ok := s.flat[key]; ok && delimiter == "." {
+		// keys of the flattened index are canonical already
+		return key, nil
+	}
+
+	steps, err := parsePath(key, delimiter)
+	if err != nil {
+		return key, nil
+	}
+	steps = s.foldSteps(steps)
+	return formatPath(steps, "."), steps
+}
+
+// externalKey converts an internal dotted key to the key delimiter of the config
+func (c *Config) externalKey(key string) string {
+	if c.delimiter == "" || c.delimiter == "." {
+		return key
+	}
+	steps, err := parsePath(key, ".")
+	if err != nil {
+		return key
+	}
+	return formatPath(steps, c.delimiter)
+}
+
+// Keys returns the keys of all values which are not objects, sorted. Arrays are
+// returned as a single key. Keys containing the key delimiter are quoted
+func (c *Config) Keys() []string {
+	keys := leafKeys(c.load().configs)
+	for index, key := range keys {
+		keys[index] = c.externalKey(key)
+	}
+	return keys
+}
+
+// Walk calls fn for every key returned by Keys with its evaluated value,
+// walking stops at the first error returned by fn
+func (c *Config) Walk(fn func(key string, value interface{}) error) error {
+	s := c.load()
+	for _, key := range leafKeys(s.configs) {
+		if err := fn(c.externalKey(key), get(s, key, nil)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConfig_EscapedKeys(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "hosts.hjson"), `{
+		"example.com": { port: 443, paths: ["/", "/api"] }
+		"a/b": { port: 80 }
+		plain: { port: 8080 }
+	}`)
+
+	configure, err := conf.New(configDir, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{`hosts."example.com".port`, `hosts["example.com"].port`, `hosts['example.com'].port`} {
+		if port := configure.GetInt(key, 0); port != 443 {
+			t.Errorf("Expected 443 for %s, got %d", key, port)
+		}
+	}
+	checkString(configure, `hosts["example.com"].paths[1]`, "/api", t)
+	if configure.IsSet("hosts.example.com.port") {
+		t.Error("Expected dots of unquoted keys to separate objects")
+	}
+	if configure.IsSet(`hosts."example.com`) {
+		t.Error("Expected invalid keys not to be set")
+	}
+
+	expected := []string{`hosts."example.com".paths`, `hosts."example.com".port`, "hosts.a/b.port", "hosts.plain.port"}
+	if keys := configure.Keys(); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected keys %v, got %v", expected, keys)
+	}
+
+	slashed, err := conf.Load(conf.WithConfigDirs(configDir), conf.WithKeyDelimiter("/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port := slashed.GetInt("hosts/example.com/port", 0); port != 443 {
+		t.Errorf("Expected 443 with a custom delimiter, got %d", port)
+	}
+	if port := slashed.GetInt(`hosts/"a/b"/port`, 0); port != 80 {
+		t.Errorf("Expected 80 for a quoted key containing the delimiter, got %d", port)
+	}
+
+	walked := make(map[string]interface{})
+	err = slashed.Walk(func(key string, value interface{}) error {
+		walked[key] = value
+		return nil
+	})
+	if err != nil || len(walked) != 4 || walked[`hosts/"a/b"/port`] != 80.0 || walked["hosts/example.com/port"] != 443.0 {
+		t.Errorf("Unexpected walked values %v", walked)
+	}
+}
@@ -0,0 +1,116 @@
+package conf
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// ProfileEnv is the environment variable selecting the active profile
+const ProfileEnv = "APP_ENV"
+
+// TestProfile is the profile used when running tests and no other profile is selected
+const TestProfile = "test"
+
+// Profiles lists the known profile names. Config directories and <name>.<profile>.hjson files
+// of known profiles are only loaded when their profile is active, add custom profiles here
+// to keep their files out of other profiles
+var Profiles = []string{"development", "staging", "production", TestProfile}
+
+// anyProfile makes loadConfigs parse the files of all profiles
+const anyProfile = "*"
+
+// fileLayer is the content of a single config file merged into a top level config
+type fileLayer struct {
+	file   string
+	values map[string]interface{}
+}
+
+// Profile returns the active profile, empty when no profile is active
+func (c *Config) Profile() string {
+	return c.load().profile
+}
+
+// SetProfile selects the active profile and reloads the configs,
+// an empty profile selects the profile from APP_ENV again.
+// The previous profile stays active if the configs of profile can not be loaded
+func (c *Config) SetProfile(profile string) error {
+	if c.frozen {
+		return errFrozen
+	}
+
+	// deferred first so callbacks run after writeMu is released
+	defer c.dispatchChanges()
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	previous := c.profile
+	c.profile = profile
+	loaded, err := c.reload()
+	if !loaded {
+		c.profile = previous
+	}
+	return err
+}
+
+func isProfile(name string, active string) bool {
+	if name == active {
+		return true
+	}
+	for _, profile := range Profiles {
+		if name == profile {
+			return true
+		}
+	}
+	return false
+}
+
+// configSource returns the dotted source name of a config file and the profile it belongs to.
+// Files inside a profile directory and files named <name>.<profile>.hjson belong to profile,
+// inDir reports the first case
+func configSource(configDir string, file string, active string) (source string, profile string, inDir bool) {
+	relative, err := filepath.Rel(configDir, file)
+	if err != nil {
+		relative = filepath.Base(file)
+	}
+	relative = filepath.ToSlash(relative)
+	relative = relative[:len(relative)-len(filepath.Ext(relative))]
+
+	parts := strings.Split(relative, "/")
+	if len(parts) > 1 && isProfile(parts[0], active) {
+		profile, parts, inDir = parts[0], parts[1:], true
+	}
+
+	last := parts[len(parts)-1]
+	if dot := strings.LastIndex(last, "."); dot > 0 && profile == "" && isProfile(last[dot+1:], active) {
+		profile = last[dot+1:]
+		parts[len(parts)-1] = last[:dot]
+	}
+
+	return strings.Join(parts, "."), profile, inDir
+}
+
+// mergeConfigs deep merges overlay into base without modifying any of them,
+// objects are merged key by key and any other value of overlay replaces the one of base
+func mergeConfigs(base map[string]interface{}, overlay map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(overlay))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overlay {
+		baseMap, baseOk := merged[key].(map[string]interface{})
+		overlayMap, overlayOk := value.(map[string]interface{})
+		if baseOk && overlayOk {
+			value = mergeConfigs(baseMap, overlayMap)
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+// envFiles returns the env files loaded for profile in the order they are applied
+func envFiles(profile string) []string {
+	if profile == "" {
+		return []string{".env"}
+	}
+	return []string{".env", ".env." + profile, ".env." + profile + ".local"}
+}
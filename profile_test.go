@@ -0,0 +1,85 @@
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfig_Profiles(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_profiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	for _, dir := range []string{"production", "staging"} {
+		if err = os.Mkdir(filepath.Join(configDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), `{
+		server: { host: "localhost", port: 8080 }
+		debug: true
+		region: env(REGION, "local")
+	}`)
+	writeConfigFile(t, filepath.Join(configDir, "production", "app.hjson"), `{
+		server: { host: "example.com" }
+	}`)
+	writeConfigFile(t, filepath.Join(configDir, "app.production.hjson"), `{
+		server: { port: 443 }
+		debug: false
+	}`)
+	writeConfigFile(t, filepath.Join(configDir, "staging", "app.hjson"), `{
+		server: { host: "staging.example.com" }
+	}`)
+	writeConfigFile(t, filepath.Join(configDir, ".env"), "REGION=base\n")
+	writeConfigFile(t, filepath.Join(configDir, ".env.production"), "REGION=eu\n")
+	writeConfigFile(t, filepath.Join(configDir, ".env.production.local"), "REGION=eu-west\n")
+
+	configure, err := conf.New(configDir, configDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile := configure.Profile(); profile != conf.TestProfile {
+		t.Errorf("Expected the test profile while testing, got %s", profile)
+	}
+	checkString(configure, "app.server.host", "localhost", t)
+	checkString(configure, "app.region", "base", t)
+
+	if err = configure.SetProfile("production"); err != nil {
+		t.Fatal(err)
+	}
+	checkString(configure, "app.server.host", "example.com", t)
+	if port := configure.GetInt("app.server.port", 0); port != 443 {
+		t.Errorf("Expected the port of app.production.hjson, got %d", port)
+	}
+	if debug := configure.GetBoolean("app.debug", true); debug {
+		t.Error("Expected debug to be disabled in production")
+	}
+	checkString(configure, "app.region", "eu-west", t)
+	if configure.IsSet("staging.app") || configure.IsSet("production.app") {
+		t.Error("Expected profile directories not to be loaded as configs")
+	}
+
+	explanation := configure.Explain("app.server.port")
+	if len(explanation.Layers) != 2 || !explanation.Layers[0].Overridden || explanation.Layers[1].Overridden ||
+		!strings.HasSuffix(explanation.Layers[1].Source, "app.production.hjson") {
+		t.Errorf("Unexpected explanation %s", explanation)
+	}
+
+	writeConfigFile(t, filepath.Join(configDir, "staging", "broken.hjson"), "{ invalid: ")
+	if err = configure.SetProfile("staging"); err == nil {
+		t.Fatal("Expected an error for the invalid staging configs")
+	}
+	if profile := configure.Profile(); profile != "production" {
+		t.Errorf("Expected the production profile to stay active, got %s", profile)
+	}
+	if err = configure.Reload(); err != nil {
+		t.Errorf("Expected reloading the previous profile, got %v", err)
+	}
+	checkString(configure, "app.server.host", "example.com", t)
+}
@@ -0,0 +1,266 @@
+package conf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LayerKind names the kind of source a value comes from
+type LayerKind string
+
+// Kinds of layers reported by Explain
+const (
+	// LayerFile is a value defined in a config file
+	LayerFile LayerKind = "file"
+	// LayerEvaluator is a value computed by an evaluator call like env(PORT)
+	LayerEvaluator LayerKind = "evaluator"
+	// LayerEnv is a value read from an environment variable
+	LayerEnv LayerKind = "env"
+	// LayerDefault is a default value given inside a config file, like 8080 in env(PORT, 8080)
+	LayerDefault LayerKind = "default"
+	// LayerOverride is a value set with Set, Source is the key it was set for
+	LayerOverride LayerKind = "override"
+)
+
+// Layer is a single step in the chain producing a config value
+type Layer struct {
+	Kind LayerKind
+	// Source describes the layer: a file path, an evaluator call or an environment variable name
+	Source string
+	// Line is the line of Source defining the value for file layers, 0 if unknown
+	Line  int
+	Value interface{}
+	// Overridden is true when a later layer replaced the value of this layer
+	Overridden bool
+}
+
+// Explanation describes where the value of a key comes from
+type Explanation struct {
+	Key   string
+	Found bool
+	// Value is the final resolved value of the key
+	Value interface{}
+	// Layers lists every layer involved, from the lowest to the one producing Value
+	Layers []Layer
+}
+
+// EvaluatorExplainer can be implemented by evaluators to describe the source of their
+// results in Explain, the env evaluator for example reports the environment variable
+type EvaluatorExplainer interface {
+	// ExplainEval returns the kind and the source of the result of Eval(params)
+	ExplainEval(params []string) (kind LayerKind, source string)
+}
+
+// Explain describes the value of key and the chain of layers producing it:
+// the file and line defining it, evaluators applied to it and their sources
+func (c *Config) Explain(key string) *Explanation {
+	s := c.load()
+	explanation := &Explanation{Key: key}
+	key = c.canonicalKey(key)
+	explanation.Value = get(s, key, nil)
+
+	raw := getRaw(s, key, nil)
+	if raw == nil {
+		return explanation
+	}
+	explanation.Found = true
+
+	// every file defining the key is a layer, profile files override the ones before them
+	layers, path := s.sourceLayers(key)
+	for _, layer := range layers {
+		value, ok := lookupSteps(layer.values, path, nil)
+		if !ok {
+			continue
+		}
+		if count := len(explanation.Layers); count > 0 {
+			explanation.Layers[count-1].Overridden = true
+		}
+		explanation.Layers = append(explanation.Layers, Layer{
+			Kind:   LayerFile,
+			Source: layer.file,
+			Line:   locateLine(layer.file, path),
+			Value:  value,
+		})
+	}
+
+	// values set with Set override all files
+	if steps, err := parsePath(key, "."); err == nil {
+		for _, o := range s.overrides {
+			value, ok := o.lookup(steps)
+			if !ok {
+				continue
+			}
+			if count := len(explanation.Layers); count > 0 {
+				explanation.Layers[count-1].Overridden = true
+			}
+			explanation.Layers = append(explanation.Layers, Layer{
+				Kind:   LayerOverride,
+				Source: c.externalKey(o.key),
+				Value:  value,
+			})
+		}
+	}
+
+	if content, ok := raw.(string); ok {
+		if methodName, params, ok := parseCall(s, content); ok {
+			evaluator := s.evaluators[methodName]
+			explanation.Layers = append(explanation.Layers, Layer{
+				Kind:   LayerEvaluator,
+				Source: strings.TrimSpace(content[:strings.Index(content, ")")+1]),
+				Value:  explanation.Value,
+			})
+			if explainer, ok := evaluator.(EvaluatorExplainer); ok {
+				kind, source := explainer.ExplainEval(params)
+				explanation.Layers = append(explanation.Layers, Layer{
+					Kind:   kind,
+					Source: source,
+					Value:  explanation.Value,
+				})
+			}
+		}
+	}
+
+	return explanation
+}
+
+// String formats the explanation as a human readable list of layers
+func (e *Explanation) String() string {
+	if !e.Found {
+		return e.Key + " is not set"
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s = %v", e.Key, e.Value)
+	for _, layer := range e.Layers {
+		source := layer.Source
+		if layer.Line > 0 {
+			source += ":" + strconv.Itoa(layer.Line)
+		}
+		fmt.Fprintf(&builder, "\n  %-9s %s => %v", layer.Kind, source, layer.Value)
+		if layer.Overridden {
+			builder.WriteString(" (overridden)")
+		}
+	}
+	return builder.String()
+}
+
+// source returns the last file defining key and the path of key inside that file
+func (s *snapshot) source(key string) (string, []pathStep) {
+	layers, path := s.sourceLayers(key)
+	if len(layers) == 0 {
+		return "", nil
+	}
+
+	for index := len(layers) - 1; index > 0; index-- {
+		if _, ok := lookupSteps(layers[index].values, path, nil); ok {
+			return layers[index].file, path
+		}
+	}
+	return layers[0].file, path
+}
+
+// sourceLayers returns the layers of the config file containing key and the path of key inside them
+func (s *snapshot) sourceLayers(key string) ([]fileLayer, []pathStep) {
+	steps, err := parsePath(key, ".")
+	if err != nil {
+		return nil, nil
+	}
+
+	// config files are named after the leading object keys of the path
+	var names []string
+	for _, step := range steps {
+		if step.kind != stepName {
+			break
+		}
+		names = append(names, step.name)
+	}
+	for length := len(names); length > 0; length-- {
+		if layers, ok := s.files[strings.Join(names[:length], ".")]; ok {
+			return layers, steps[length:]
+		}
+	}
+	return nil, nil
+}
+
+// locateLine finds the line defining the object path inside file on a best effort basis,
+// by searching each key of the path after the position of its parent
+func locateLine(file string, path []pathStep) int {
+	if file == "" || len(path) == 0 {
+		return 0
+	}
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0
+	}
+
+	text := string(content)
+	offset := 0
+	for _, step := range path {
+		if step.kind == stepIndex {
+			if next, ok := locateElement(text, offset, step.index); ok {
+				offset = next
+			}
+			continue
+		}
+
+		quoted := regexp.QuoteMeta(step.name)
+		pattern, err := regexp.Compile(`(^|[{,\s])("` + quoted + `"|'` + quoted + `'|` + quoted + `)\s*:`)
+		if err != nil {
+			return 0
+		}
+		location := pattern.FindStringIndex(text[offset:])
+		if location == nil {
+			return 0
+		}
+		offset += location[1]
+	}
+
+	return strings.Count(text[:offset], "\n") + 1
+}
+
+// locateElement returns the position of the object or array at index of the array
+// starting after offset. Scalar elements are not located since hjson does not need
+// separators between them
+func locateElement(text string, offset int, index int) (int, bool) {
+	start := strings.IndexByte(text[offset:], '[')
+	if start < 0 {
+		return 0, false
+	}
+
+	depth, count := 0, 0
+	var quote byte
+	for position := offset + start + 1; position < len(text); position++ {
+		char := text[position]
+		if quote != 0 {
+			if char == '\\' {
+				position++
+			} else if char == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch char {
+		case '"', '\'':
+			quote = char
+		case '{', '[':
+			if depth == 0 {
+				if count == index {
+					return position + 1, true
+				}
+				count++
+			}
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				return 0, false
+			}
+			depth--
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,61 @@
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfig_Explain(t *testing.T) {
+	root, err := os.Executable()
+	if err != nil {
+		t.Error(err)
+	}
+
+	rootDir := filepath.Join(filepath.Dir(root), "..")
+	t.Log("Searching Config files at: " + rootDir)
+
+	configure, err := conf.New(filepath.Join(rootDir, "test_configs/valids"), rootDir, []conf.EvaluatorFunction{
+		new(testEvalFunction),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	explanation := configure.Explain("evaluators.env.host")
+	t.Log(explanation)
+	if !explanation.Found || explanation.Value != "testhost" || len(explanation.Layers) != 3 {
+		t.Fatalf("Unexpected explanation %+v", explanation)
+	}
+	file := explanation.Layers[0]
+	if file.Kind != conf.LayerFile || !strings.HasSuffix(file.Source, "evaluators.hjson") || file.Line != 7 || !strings.HasPrefix(file.Value.(string), "env(HOST)") {
+		t.Errorf("Unexpected file layer %+v", file)
+	}
+	if evaluator := explanation.Layers[1]; evaluator.Kind != conf.LayerEvaluator || evaluator.Source != "env(HOST)" {
+		t.Errorf("Unexpected evaluator layer %+v", evaluator)
+	}
+	if env := explanation.Layers[2]; env.Kind != conf.LayerEnv || env.Source != "HOST" || env.Value != "testhost" {
+		t.Errorf("Unexpected env layer %+v", env)
+	}
+
+	explanation = configure.Explain("evaluators.env.instance_in_conf_default")
+	if last := explanation.Layers[len(explanation.Layers)-1]; last.Kind != conf.LayerDefault || last.Source != "in conf default" {
+		t.Errorf("Unexpected default layer %+v", last)
+	}
+
+	explanation = configure.Explain("nested.objects[1].integer")
+	if explanation.Value != 200.0 || len(explanation.Layers) != 1 || explanation.Layers[0].Line != 12 {
+		t.Errorf("Unexpected explanation %s", explanation)
+	}
+
+	explanation = configure.Explain("dir.inner.inside.value")
+	if len(explanation.Layers) != 1 || !strings.HasSuffix(explanation.Layers[0].Source, "inside.hjson") || explanation.Layers[0].Line != 2 {
+		t.Errorf("Unexpected explanation %s", explanation)
+	}
+
+	if explanation = configure.Explain("do.not.exist"); explanation.Found || len(explanation.Layers) != 0 {
+		t.Errorf("Unexpected explanation for a missing key %s", explanation)
+	}
+}
@@ -0,0 +1,244 @@
+package conf
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// filter operators, two character operators first so they are matched before < and >
+var filterOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// pathFilter is the condition of a [?field op value] step, a filter without
+// an operator matches elements having field. Fields are always separated by dots
+type pathFilter struct {
+	raw      string
+	field    []pathStep
+	operator string
+	value    interface{}
+}
+
+func parseFilter(raw string) (*pathFilter, error) {
+	filter := &pathFilter{raw: raw}
+
+	field := raw
+	if position, operator := findOperator(raw); position >= 0 {
+		field = raw[:position]
+		filter.operator = operator
+		value, err := parseLiteral(strings.TrimSpace(raw[position+len(operator):]))
+		if err != nil {
+			return nil, err
+		}
+		filter.value = value
+	}
+
+	field = strings.TrimSpace(field)
+	if field == "" {
+		return nil, errors.New("expected a field in filter")
+	}
+	steps, err := parsePath(field, ".")
+	if err != nil {
+		return nil, err
+	}
+	filter.field = steps
+	return filter, nil
+}
+
+// findOperator returns the position of the first operator of raw outside of quotes
+func findOperator(raw string) (int, string) {
+	var quote byte
+	for position := 0; position < len(raw); position++ {
+		char := raw[position]
+		if quote != 0 {
+			if char == '\\' {
+				position++
+			} else if char == quote {
+				quote = 0
+			}
+			continue
+		}
+		if char == '"' || char == '\'' {
+			quote = char
+			continue
+		}
+		for _, operator := range filterOperators {
+			if strings.HasPrefix(raw[position:], operator) {
+				return position, operator
+			}
+		}
+	}
+	return -1, ""
+}
+
+// parseLiteral parses the value of a filter: a quoted string, a number, true, false or null
+func parseLiteral(raw string) (interface{}, error) {
+	switch {
+	case raw == "":
+		return nil, errors.New("expected a value in filter")
+	case raw[0] == '"' || raw[0] == '\'':
+		value, next, err := parseQuoted(raw, 0)
+		if err != nil {
+			return nil, err
+		}
+		if next != len(raw) {
+			return nil, errors.New("unexpected characters after " + raw[:next])
+		}
+		return value, nil
+	case raw == "true":
+		return true, nil
+	case raw == "false":
+		return false, nil
+	case raw == "null":
+		return nil, nil
+	}
+
+	number, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, errors.New("invalid value " + raw + " in filter, quote strings")
+	}
+	return number, nil
+}
+
+// matches reports if the evaluated field of item, found at path, satisfies the filter
+func (f *pathFilter) matches(s *snapshot, path []pathStep, item interface{}) bool {
+	value, ok := lookupSteps(item, f.field, s.keyIndex)
+	if !ok {
+		return false
+	}
+	if str, isString := value.(string); isString {
+		literal := len(s.literals) > 0 && s.literals[formatPath(append(path[:len(path):len(path)], f.field...), ".")]
+		if !literal {
+			value = evalStringValue(s, str, nil)
+		}
+	}
+	if f.operator == "" {
+		return value != nil
+	}
+
+	switch f.operator {
+	case "==":
+		return reflect.DeepEqual(value, f.value)
+	case "!=":
+		return !reflect.DeepEqual(value, f.value)
+	}
+
+	var compared int
+	switch expected := f.value.(type) {
+	case float64:
+		actual, ok := value.(float64)
+		if !ok {
+			return false
+		}
+		compared = compareFloats(actual, expected)
+	case string:
+		actual, ok := value.(string)
+		if !ok {
+			return false
+		}
+		compared = strings.Compare(actual, expected)
+	default:
+		return false
+	}
+
+	switch f.operator {
+	case "<":
+		return compared < 0
+	case "<=":
+		return compared <= 0
+	case ">":
+		return compared > 0
+	default:
+		return compared >= 0
+	}
+}
+
+func compareFloats(a float64, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// queryMatch is a value selected by a query and its path without wildcards and filters
+type queryMatch struct {
+	path  []pathStep
+	value interface{}
+}
+
+// query returns every value selected by steps inside value, in document order for
+// arrays and key order for objects
+func query(s *snapshot, value interface{}, steps []pathStep, path []pathStep, matches []queryMatch) []queryMatch {
+	if len(steps) == 0 {
+		return append(matches, queryMatch{path: path, value: value})
+	}
+
+	step := steps[0]
+	child := func(next pathStep, item interface{}) {
+		matches = query(s, item, steps[1:], append(path[:len(path):len(path)], next), matches)
+	}
+
+	switch step.kind {
+	case stepName, stepIndex:
+		if item, ok := lookupSteps(value, steps[:1], s.keyIndex); ok {
+			if step.kind == stepIndex {
+				step.index, _ = arrayIndex(value.([]interface{}), step.index)
+			}
+			child(step, item)
+		}
+
+	case stepWildcard, stepFilter:
+		switch typed := value.(type) {
+		case []interface{}:
+			for index, item := range typed {
+				next := pathStep{kind: stepIndex, index: index}
+				if step.kind == stepWildcard || step.filter.matches(s, append(path[:len(path):len(path)], next), item) {
+					child(next, item)
+				}
+			}
+		case map[string]interface{}:
+			names := make([]string, 0, len(typed))
+			for name := range typed {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				next := pathStep{name: name}
+				if step.kind == stepWildcard || step.filter.matches(s, append(path[:len(path):len(path)], next), typed[name]) {
+					child(next, typed[name])
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// Query returns the evaluated values selected by key, which may contain wildcards
+// and filters, in document order for arrays and key order for objects
+//
+//	config.Query(`app.servers[*].port`)
+//	config.Query(`app.servers[?role=="primary"].host`)
+//	config.Query(`app.matrix[-1][0]`)
+func (c *Config) Query(key string) ([]interface{}, error) {
+	delimiter := c.delimiter
+	if delimiter == "" {
+		delimiter = "."
+	}
+	steps, err := parsePath(key, delimiter)
+	if err != nil {
+		return nil, err
+	}
+
+	s := c.load()
+	matches := query(s, s.configs, steps, nil, nil)
+	values := make([]interface{}, len(matches))
+	for index, match := range matches {
+		c.usage.record(formatPath(match.path, "."))
+		values[index] = resolveValue(s, formatPath(match.path, "."), match.value)
+	}
+	return values, nil
+}
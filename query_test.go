@@ -0,0 +1,78 @@
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConfig_Query(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), `{
+		matrix: [[1, 2], [3, 4, 5]]
+		servers: [
+			{ name: "a", role: "primary", port: 8080 }
+			{ name: "b", role: "replica", port: 8081 }
+			{ name: "c", role: "replica", port: 9090, tags: ["slow"] }
+		]
+		users: { bob: { admin: true }, alice: { admin: false } }
+	}`)
+
+	configure, err := conf.New(configDir, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value := configure.GetInt("app.matrix[1][2]", 0); value != 5 {
+		t.Errorf("Expected 5 for chained indexes, got %d", value)
+	}
+	if value := configure.GetInt("app.matrix[-1][-3]", 0); value != 3 {
+		t.Errorf("Expected 3 for negative indexes, got %d", value)
+	}
+	checkString(configure, "app.servers[-1].name", "c", t)
+	if configure.IsSet("app.matrix[0][-3]") {
+		t.Error("Expected out of range negative indexes not to be set")
+	}
+
+	queries := map[string][]interface{}{
+		"app.servers[*].name":                {"a", "b", "c"},
+		`app.servers[?role=="replica"].name`: {"b", "c"},
+		"app.servers[?port>=8081].port":      {8081.0, 9090.0},
+		`app.servers[?role!="primary"][0]`:   nil,
+		"app.servers[?tags].name":            {"c"},
+		`app.servers[?tags[0]=="slow"].name`: {"c"},
+		"app.users.*.admin":                  {false, true},
+		"app.users[?admin==true]":            {map[string]interface{}{"admin": true}},
+		"app.matrix[*][0]":                   {1.0, 3.0},
+		"app.servers[?role==\"none\"].name":  {},
+	}
+	for key, expected := range queries {
+		values, err := configure.Query(key)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", key, err)
+			continue
+		}
+		if expected == nil {
+			expected = []interface{}{}
+		}
+		if !reflect.DeepEqual(values, expected) {
+			t.Errorf("Expected %v for %s, got %v", expected, key, values)
+		}
+	}
+
+	names, ok := configure.Get("app.servers[*].name", nil).([]interface{})
+	if !ok || len(names) != 3 {
+		t.Errorf("Expected Get to return all matches, got %v", names)
+	}
+	if _, err = configure.Query("app.servers[?role==]"); err == nil {
+		t.Error("Expected an error for an invalid filter")
+	}
+}
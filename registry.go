@@ -0,0 +1,105 @@
+package conf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RegisterEvaluator adds fn to the evaluators, calls of it in the configs are evaluated on the
+// next read. It fails with *EvaluatorExistsError when an evaluator with the same name is registered
+func (c *Config) RegisterEvaluator(fn EvaluatorFunction) error {
+	return c.RegisterNamespace("", fn)
+}
+
+// RegisterNamespace adds evaluators named namespace.name, like myapp.join(a, b) for a join
+// evaluator in the myapp namespace. Either all evaluators are registered or none of them.
+// With eager evaluation the calls are evaluated and validated against the schemas right away,
+// and the evaluators are not registered if that fails
+func (c *Config) RegisterNamespace(namespace string, fns ...EvaluatorFunction) error {
+	if c.frozen {
+		return errFrozen
+	}
+	if namespace != "" && !isEvaluatorName(namespace) {
+		return fmt.Errorf("conf: invalid evaluator namespace %q", namespace)
+	}
+
+	// deferred first so callbacks run after writeMu is released
+	defer c.dispatchChanges()
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	old := c.load()
+	evaluators := make(map[string]EvaluatorFunction, len(old.evaluators)+len(fns))
+	for name, evaluator := range old.evaluators {
+		evaluators[name] = evaluator
+	}
+	for _, fn := range fns {
+		name := fn.GetFunctionName()
+		if !isEvaluatorName(name) {
+			return fmt.Errorf("conf: invalid evaluator name %q", name)
+		}
+		if namespace != "" {
+			name = namespace + "." + name
+		}
+		if _, ok := evaluators[name]; ok {
+			return &EvaluatorExistsError{Name: name}
+		}
+		evaluators[name] = fn
+	}
+
+	next, err := c.withEvaluators(old, evaluators)
+	if err != nil {
+		return err
+	}
+	c.swap(old, next)
+	return nil
+}
+
+// UnregisterEvaluator removes the evaluator called name (like myapp.join for namespaced ones),
+// its calls are read as plain strings afterwards. It returns false if there is no such evaluator,
+// or if with eager evaluation the configs are not valid without it
+func (c *Config) UnregisterEvaluator(name string) bool {
+	if c.frozen {
+		return false
+	}
+
+	// deferred first so callbacks run after writeMu is released
+	defer c.dispatchChanges()
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	old := c.load()
+	if _, ok := old.evaluators[name]; !ok {
+		return false
+	}
+	evaluators := make(map[string]EvaluatorFunction, len(old.evaluators))
+	for registered, evaluator := range old.evaluators {
+		if registered != name {
+			evaluators[registered] = evaluator
+		}
+	}
+
+	next, err := c.withEvaluators(old, evaluators)
+	if err != nil {
+		return false
+	}
+	c.swap(old, next)
+	return true
+}
+
+// withEvaluators returns a copy of s using evaluators. Eagerly evaluated configs, schema
+// validation and cached results depend on the evaluators so the copy is prepared again
+func (c *Config) withEvaluators(s *snapshot, evaluators map[string]EvaluatorFunction) (*snapshot, error) {
+	next := *s
+	next.evaluators = bindEnv(evaluators, s.env)
+	next.cache = newEvalCache()
+	if err := c.prepareSnapshot(&next); err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+// isEvaluatorName reports if name can be used in calls inside config files
+func isEvaluatorName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, "()\"'\\, \t\r\n")
+}
@@ -0,0 +1,163 @@
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// testEnvEvaluator is an env evaluator returning the names of the variables
+type testEnvEvaluator struct {
+}
+
+func (e *testEnvEvaluator) GetFunctionName() string {
+	return "env"
+}
+func (e *testEnvEvaluator) Eval(params []string, def interface{}) interface{} {
+	return "variable " + params[0]
+}
+
+func TestLoad_EvaluatorCollisions(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_collisions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), `{
+		host: env(CONF_TEST_COLLISION_HOST, "localhost")
+	}`)
+
+	_, err = conf.Load(conf.WithConfigDirs(configDir), conf.WithEvaluators(new(testEvalFunction), new(testEvalFunction)))
+	if err, ok := err.(*conf.EvaluatorExistsError); !ok || err.Name != "paramsJoin" {
+		t.Errorf("Expected a collision error for duplicate evaluators, got %v", err)
+	}
+	_, err = conf.Load(conf.WithConfigDirs(configDir), conf.WithEvaluators(new(testEnvEvaluator)))
+	if err, ok := err.(*conf.EvaluatorExistsError); !ok || err.Name != "env" {
+		t.Errorf("Expected a collision error for the env evaluator, got %v", err)
+	}
+
+	configure, err := conf.Load(conf.WithConfigDirs(configDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !configure.UnregisterEvaluator("env") {
+		t.Fatal("Expected the env evaluator to be unregistered")
+	}
+	if err = configure.RegisterEvaluator(new(testEnvEvaluator)); err != nil {
+		t.Fatal(err)
+	}
+	checkString(configure, "app.host", "variable CONF_TEST_COLLISION_HOST", t)
+}
+
+func TestConfig_RegisterEvaluator(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), `{
+		joined: paramsJoin(a, b)
+		namespaced: myapp.paramsJoin(c, d)
+	}`)
+
+	configure, err := conf.Load(conf.WithConfigDirs(configDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkString(configure, "app.joined", "paramsJoin(a, b)", t)
+
+	changes := make(chan interface{}, 10)
+	configure.OnChange("app.joined", func(old interface{}, new interface{}) {
+		changes <- new
+	})
+
+	if err = configure.RegisterEvaluator(new(testEvalFunction)); err != nil {
+		t.Fatal(err)
+	}
+	checkString(configure, "app.joined", "a:b", t)
+	if change := <-changes; change != "a:b" {
+		t.Errorf("Expected a change to a:b, got %v", change)
+	}
+
+	if err, ok := configure.RegisterEvaluator(new(testEvalFunction)).(*conf.EvaluatorExistsError); !ok || err.Name != "paramsJoin" {
+		t.Errorf("Expected a collision error, got %v", err)
+	}
+	if err = configure.RegisterNamespace("my app", new(testEvalFunction)); err == nil {
+		t.Error("Expected an error for an invalid namespace")
+	}
+	if err = configure.RegisterNamespace("myapp", new(testEvalFunction)); err != nil {
+		t.Fatal(err)
+	}
+	checkString(configure, "app.namespaced", "c:d", t)
+	if _, ok := configure.EvaluatorFunctionsMap()["myapp.paramsJoin"]; !ok {
+		t.Error("Expected the namespaced evaluator in the evaluators map")
+	}
+
+	if err = configure.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	checkString(configure, "app.namespaced", "c:d", t)
+
+	if !configure.UnregisterEvaluator("paramsJoin") {
+		t.Error("Expected paramsJoin to be unregistered")
+	}
+	if configure.UnregisterEvaluator("paramsJoin") {
+		t.Error("Expected no evaluator to unregister")
+	}
+	checkString(configure, "app.joined", "paramsJoin(a, b)", t)
+	checkString(configure, "app.namespaced", "c:d", t)
+
+	if err = configure.Snapshot().RegisterEvaluator(new(countingEvaluator)); err == nil {
+		t.Error("Expected an error registering evaluators of a snapshot")
+	}
+}
+
+func TestConfig_ConcurrentRegisterEvaluator(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_registry_race")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), "joined: paramsJoin(a, b)")
+
+	configure, err := conf.Load(conf.WithConfigDirs(configDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if joined := configure.GetString("app.joined", ""); joined != "a:b" && joined != "paramsJoin(a, b)" {
+					t.Errorf("Unexpected value %s", joined)
+					return
+				}
+				configure.EvaluatorFunctionsMap()
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		if err = configure.RegisterEvaluator(new(testEvalFunction)); err != nil {
+			t.Error(err)
+		}
+		configure.UnregisterEvaluator("paramsJoin")
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,504 @@
+package conf
+
+import (
+	"fmt"
+	"github.com/hjson/hjson-go"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// SchemaDir is the directory inside the config directory holding JSON Schema documents.
+// A schema named app.schema.json (or app.schema.hjson) validates the top level config app
+const SchemaDir = "schemas"
+
+const schemaSuffix = ".schema"
+
+// Schema is a parsed JSON Schema document.
+// A subset of draft 2020-12 is supported: type, enum, const, properties, required,
+// additionalProperties, patternProperties, minProperties, maxProperties, items, prefixItems,
+// minItems, maxItems, uniqueItems, minLength, maxLength, pattern, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, multipleOf, allOf, anyOf, oneOf, not
+// and local $ref pointers like "#/$defs/port"
+type Schema struct {
+	root     interface{}
+	patterns map[string]*regexp.Regexp
+}
+
+// ValidationError describes a single invalid config value
+type ValidationError struct {
+	// Key is the dotted config key of the invalid value, like app.server.port or app.hosts[1]
+	Key string
+	// File is the config file defining the key, empty if unknown
+	File    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.File != "" {
+		return e.Key + " (" + e.File + "): " + e.Message
+	}
+	return e.Key + ": " + e.Message
+}
+
+// ValidationErrors holds all violations found while validating configs
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for index, err := range e {
+		messages[index] = err.Error()
+	}
+	return "conf: invalid configuration:\n" + strings.Join(messages, "\n")
+}
+
+// LoadSchema reads and parses the JSON Schema document at path,
+// hjson syntax is accepted as well
+func LoadSchema(path string) (*Schema, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := ParseSchema(content)
+	if err != nil {
+		return nil, fmt.Errorf("conf: invalid schema %s: %v", path, err)
+	}
+	return schema, nil
+}
+
+// ParseSchema parses a JSON Schema document
+func ParseSchema(content []byte) (*Schema, error) {
+	var root interface{}
+	if err := hjson.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+
+	schema := &Schema{
+		root:     root,
+		patterns: make(map[string]*regexp.Regexp),
+	}
+	if err := schema.compilePatterns(root); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// Validate checks value against the schema and returns all violations,
+// keys of violations are prefixed with prefix
+func (s *Schema) Validate(prefix string, value interface{}) ValidationErrors {
+	var errs ValidationErrors
+	s.validate(s.root, prefix, value, &errs)
+	return errs
+}
+
+// Validate checks the evaluated config name (a top level config, like app for app.hjson)
+// against schema. The returned error is of type ValidationErrors
+func (c *Config) Validate(name string, schema *Schema) error {
+	s := c.load()
+	if errs := schema.Validate(name, resolveValue(s, joinKey("", name), s.configs[name])); len(errs) > 0 {
+		s.addSourceFiles(errs)
+		return errs
+	}
+	return nil
+}
+
+// loadSchemas parses all schemas found in the SchemaDir of configDir
+func loadSchemas(configDir string) (map[string]*Schema, error) {
+	schemaDir := filepath.Join(configDir, SchemaDir)
+	files, err := ioutil.ReadDir(schemaDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := make(map[string]*Schema)
+	for _, file := range files {
+		if file.IsDir() || !isSchemaFile(file.Name()) {
+			continue
+		}
+
+		schema, err := LoadSchema(filepath.Join(schemaDir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
+		schemas[strings.TrimSuffix(name, schemaSuffix)] = schema
+	}
+	return schemas, nil
+}
+
+func isSchemaFile(filename string) bool {
+	name := strings.TrimSuffix(filename, filepath.Ext(filename))
+	return strings.HasSuffix(name, schemaSuffix)
+}
+
+// validateSchemas validates every config having a schema
+func validateSchemas(s *snapshot, schemas map[string]*Schema) error {
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs ValidationErrors
+	for _, name := range names {
+		errs = append(errs, schemas[name].Validate(name, resolveValue(s, joinKey("", name), s.configs[name]))...)
+	}
+	if len(errs) > 0 {
+		s.addSourceFiles(errs)
+		return errs
+	}
+	return nil
+}
+
+func (s *snapshot) addSourceFiles(errs ValidationErrors) {
+	for _, err := range errs {
+		err.File = s.sourceFile(err.Key)
+	}
+}
+
+func (s *Schema) compilePatterns(node interface{}) error {
+	switch typed := node.(type) {
+	case map[string]interface{}:
+		if pattern, ok := typed["pattern"].(string); ok {
+			if err := s.compile(pattern); err != nil {
+				return err
+			}
+		}
+		if patternProperties, ok := typed["patternProperties"].(map[string]interface{}); ok {
+			for pattern := range patternProperties {
+				if err := s.compile(pattern); err != nil {
+					return err
+				}
+			}
+		}
+		for _, child := range typed {
+			if err := s.compilePatterns(child); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, child := range typed {
+			if err := s.compilePatterns(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) compile(pattern string) error {
+	if _, ok := s.patterns[pattern]; ok {
+		return nil
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	s.patterns[pattern] = compiled
+	return nil
+}
+
+func (s *Schema) validate(node interface{}, key string, value interface{}, errs *ValidationErrors) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, &ValidationError{Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch typed := node.(type) {
+	case bool:
+		if !typed {
+			fail("no value is allowed")
+		}
+		return
+	case map[string]interface{}:
+	default:
+		return
+	}
+	schema := node.(map[string]interface{})
+
+	if ref, ok := schema["$ref"].(string); ok {
+		target, err := s.resolveRef(ref)
+		if err != nil {
+			fail("%v", err)
+		} else {
+			s.validate(target, key, value, errs)
+		}
+	}
+
+	if types, ok := schema["type"]; ok && !matchesType(types, value) {
+		fail("expected %s but found %s", describeTypes(types), jsonType(value))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, item := range enum {
+			if reflect.DeepEqual(item, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value %v is not one of %v", value, enum)
+		}
+	}
+	if constant, ok := schema["const"]; ok && !reflect.DeepEqual(constant, value) {
+		fail("value %v must be %v", value, constant)
+	}
+
+	switch typed := value.(type) {
+	case string:
+		s.validateString(schema, typed, fail)
+	case float64:
+		validateNumber(schema, typed, fail)
+	case []interface{}:
+		s.validateArray(schema, key, typed, errs, fail)
+	case map[string]interface{}:
+		s.validateObject(schema, key, typed, errs, fail)
+	}
+
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range allOf {
+			s.validate(sub, key, value, errs)
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		if s.countMatches(anyOf, key, value) == 0 {
+			fail("value does not match any of the anyOf schemas")
+		}
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		if matches := s.countMatches(oneOf, key, value); matches != 1 {
+			fail("value must match exactly one of the oneOf schemas but matches %d", matches)
+		}
+	}
+	if not, ok := schema["not"]; ok {
+		if s.countMatches([]interface{}{not}, key, value) == 1 {
+			fail("value must not match the not schema")
+		}
+	}
+}
+
+func (s *Schema) validateString(schema map[string]interface{}, value string, fail func(string, ...interface{})) {
+	length := float64(utf8.RuneCountInString(value))
+	if min, ok := schema["minLength"].(float64); ok && length < min {
+		fail("length must be at least %v", min)
+	}
+	if max, ok := schema["maxLength"].(float64); ok && length > max {
+		fail("length must be at most %v", max)
+	}
+	if pattern, ok := schema["pattern"].(string); ok && !s.patterns[pattern].MatchString(value) {
+		fail("value %q does not match pattern %s", value, pattern)
+	}
+}
+
+func validateNumber(schema map[string]interface{}, value float64, fail func(string, ...interface{})) {
+	if min, ok := schema["minimum"].(float64); ok && value < min {
+		fail("value %v must be at least %v", value, min)
+	}
+	if max, ok := schema["maximum"].(float64); ok && value > max {
+		fail("value %v must be at most %v", value, max)
+	}
+	if min, ok := schema["exclusiveMinimum"].(float64); ok && value <= min {
+		fail("value %v must be greater than %v", value, min)
+	}
+	if max, ok := schema["exclusiveMaximum"].(float64); ok && value >= max {
+		fail("value %v must be less than %v", value, max)
+	}
+	if multiple, ok := schema["multipleOf"].(float64); ok && multiple > 0 {
+		if quotient := value / multiple; quotient != math.Trunc(quotient) {
+			fail("value %v must be a multiple of %v", value, multiple)
+		}
+	}
+}
+
+func (s *Schema) validateArray(schema map[string]interface{}, key string, value []interface{}, errs *ValidationErrors, fail func(string, ...interface{})) {
+	length := float64(len(value))
+	if min, ok := schema["minItems"].(float64); ok && length < min {
+		fail("must have at least %v items", min)
+	}
+	if max, ok := schema["maxItems"].(float64); ok && length > max {
+		fail("must have at most %v items", max)
+	}
+	if unique, ok := schema["uniqueItems"].(bool); ok && unique {
+		for i := range value {
+			for j := i + 1; j < len(value); j++ {
+				if reflect.DeepEqual(value[i], value[j]) {
+					fail("items %d and %d are equal", i, j)
+				}
+			}
+		}
+	}
+
+	prefixItems, _ := schema["prefixItems"].([]interface{})
+	for index, item := range value {
+		itemKey := indexKey(key, index)
+		if index < len(prefixItems) {
+			s.validate(prefixItems[index], itemKey, item, errs)
+		} else if items, ok := schema["items"]; ok {
+			s.validate(items, itemKey, item, errs)
+		}
+	}
+}
+
+func (s *Schema) validateObject(schema map[string]interface{}, key string, value map[string]interface{}, errs *ValidationErrors, fail func(string, ...interface{})) {
+	length := float64(len(value))
+	if min, ok := schema["minProperties"].(float64); ok && length < min {
+		fail("must have at least %v properties", min)
+	}
+	if max, ok := schema["maxProperties"].(float64); ok && length > max {
+		fail("must have at most %v properties", max)
+	}
+
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, found := value[name]; !found {
+					*errs = append(*errs, &ValidationError{Key: joinKey(key, name), Message: "required key is missing"})
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	patternProperties, _ := schema["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := schema["additionalProperties"]
+
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		childKey := joinKey(key, name)
+		matched := false
+		if property, ok := properties[name]; ok {
+			matched = true
+			s.validate(property, childKey, value[name], errs)
+		}
+		for pattern, property := range patternProperties {
+			if s.patterns[pattern].MatchString(name) {
+				matched = true
+				s.validate(property, childKey, value[name], errs)
+			}
+		}
+		if !matched && hasAdditional {
+			if allowed, ok := additional.(bool); ok && !allowed {
+				*errs = append(*errs, &ValidationError{Key: childKey, Message: "unknown key"})
+			} else {
+				s.validate(additional, childKey, value[name], errs)
+			}
+		}
+	}
+}
+
+func (s *Schema) countMatches(schemas []interface{}, key string, value interface{}) int {
+	matches := 0
+	for _, sub := range schemas {
+		var subErrs ValidationErrors
+		s.validate(sub, key, value, &subErrs)
+		if len(subErrs) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+// resolveRef follows local JSON pointers like #/$defs/name
+func (s *Schema) resolveRef(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("only local schema references are supported, found %s", ref)
+	}
+
+	node := s.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.Replace(strings.Replace(part, "~1", "/", -1), "~0", "~", -1)
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("schema reference %s not found", ref)
+		}
+		if node, ok = object[part]; !ok {
+			return nil, fmt.Errorf("schema reference %s not found", ref)
+		}
+	}
+	return node, nil
+}
+
+func matchesType(types interface{}, value interface{}) bool {
+	switch typed := types.(type) {
+	case string:
+		return matchesSingleType(typed, value)
+	case []interface{}:
+		for _, item := range typed {
+			if name, ok := item.(string); ok && matchesSingleType(name, value) {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+func matchesSingleType(name string, value interface{}) bool {
+	actual := jsonType(value)
+	if name == "number" && actual == "integer" {
+		return true
+	}
+	return name == actual
+}
+
+func describeTypes(types interface{}) string {
+	if list, ok := types.([]interface{}); ok {
+		names := make([]string, len(list))
+		for index, item := range list {
+			names[index] = fmt.Sprintf("%v", item)
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprintf("%v", types)
+}
+
+func jsonType(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if typed == math.Trunc(typed) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// joinKey returns the dotted key of the child name of prefix, quoting name when needed
+func joinKey(prefix string, name string) string {
+	if prefix == "" {
+		return formatName(name, ".")
+	}
+	return prefix + "." + formatName(name, ".")
+}
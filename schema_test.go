@@ -0,0 +1,95 @@
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSchemaValidation(t *testing.T) {
+	root, err := os.Executable()
+	if err != nil {
+		t.Error(err)
+	}
+
+	rootDir := filepath.Join(filepath.Dir(root), "..")
+	t.Log("Searching Config files at: " + rootDir)
+
+	configure, err := conf.New(filepath.Join(rootDir, "test_configs/schema_invalids"), "", nil)
+	if err == nil || configure != nil {
+		t.Fatal("Expected schema violations but config loaded")
+	}
+
+	errs, ok := err.(conf.ValidationErrors)
+	if !ok {
+		t.Fatalf("Expected ValidationErrors, got %T: %v", err, err)
+	}
+	t.Log(err)
+
+	expected := map[string]bool{
+		"app.debug":       true,
+		"app.server.port": true,
+		"app.server.host": true,
+		"app.server.prot": true,
+		"app.hosts":       true,
+	}
+	for _, violation := range errs {
+		if !expected[violation.Key] {
+			t.Errorf("Unexpected violation %s", violation)
+		}
+		delete(expected, violation.Key)
+	}
+	for key := range expected {
+		t.Errorf("Missing violation for key %s", key)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	root, err := os.Executable()
+	if err != nil {
+		t.Error(err)
+	}
+
+	rootDir := filepath.Join(filepath.Dir(root), "..")
+	t.Log("Searching Config files at: " + rootDir)
+
+	configure, err := conf.New(filepath.Join(rootDir, "test_configs/valids"), rootDir, []conf.EvaluatorFunction{
+		new(testEvalFunction),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if configure.IsSet("schemas.nested") {
+		t.Error("Schema files should not be loaded as configs")
+	}
+
+	schema, err := conf.ParseSchema([]byte(`{
+		properties: {
+			env: {
+				properties: {
+					port: { type: "string", pattern: "^[0-9]+$" }
+					server: { const: "Gitlab" }
+				}
+			}
+			testEval: { oneOf: [{ type: "string" }, { maxLength: 100 }] }
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = configure.Validate("evaluators", schema)
+	errs, ok := err.(conf.ValidationErrors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("Expected two violations, got %v", err)
+	}
+	if errs[0].Key != "evaluators.env.server" || errs[1].Key != "evaluators.testEval" {
+		t.Errorf("Unexpected violations: %v", errs)
+	}
+
+	if _, err = conf.ParseSchema([]byte(`{ pattern: "[" }`)); err == nil {
+		t.Error("Expected an error for an invalid schema pattern")
+	}
+}
@@ -0,0 +1,196 @@
+package conf
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var hostnamePattern = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*\.?$`)
+
+// Unmarshal decodes the evaluated config at key into out, which must be a non nil pointer.
+// An empty key decodes all configs.
+//
+// Struct fields are matched with the key named in their `conf:"name"` tag, or case insensitively
+// with their field name; `conf:"-"` skips a field. time.Duration fields accept strings like "1m30s".
+// Fields can declare validation rules in a `validate` tag, separated by commas:
+//
+//	required      the key must exist
+//	min=n, max=n  bounds for numbers, or for the length of strings, arrays and maps
+//	oneof=a b c   the value must be one of the space separated values
+//	url           the value must be an absolute url
+//	hostname      the value must be a valid host name
+//	duration>=1s  bounds for time.Duration fields, with >=, >, <= or <
+//
+// All decoding and validation failures are returned as ValidationErrors
+// holding the dotted config key and the file the key is defined in
+func (c *Config) Unmarshal(key string, out interface{}) error {
+	target := reflect.ValueOf(out)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return errors.New("conf: Unmarshal needs a non nil pointer")
+	}
+
+	key = c.canonicalKey(key)
+	s := c.load()
+	var value interface{}
+	if key == "" {
+		value = resolveValue(s, "", s.configs)
+	} else {
+		value = get(s, key, nil)
+	}
+
+	d := &decoder{s: s, usage: c.usage}
+	d.decode(key, value, target.Elem())
+	if len(d.errs) > 0 {
+		return d.errs
+	}
+	return nil
+}
+
+// validateField checks the validate tag rules of field against its decoded value
+func (d *decoder) validateField(key string, field reflect.StructField, found bool, value reflect.Value) {
+	tag := field.Tag.Get("validate")
+	if tag == "" {
+		return
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "required" {
+			if !found {
+				d.fail(key, "required key is missing")
+				return
+			}
+			continue
+		}
+		if !found {
+			continue
+		}
+
+		for value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return
+			}
+			value = value.Elem()
+		}
+
+		if err := checkRule(rule, value); err != "" {
+			d.fail(key, "%s", err)
+		}
+	}
+}
+
+// checkRule validates value against a single rule and returns the failure message
+func checkRule(rule string, value reflect.Value) string {
+	switch {
+	case strings.HasPrefix(rule, "min="), strings.HasPrefix(rule, "max="):
+		limit, err := strconv.ParseFloat(rule[4:], 64)
+		if err != nil {
+			return "invalid validation rule " + rule
+		}
+		size, ok := measure(value)
+		if !ok {
+			return "rule " + rule + " can not be applied to " + value.Type().String()
+		}
+		if rule[:3] == "min" && size < limit {
+			return "value must be at least " + rule[4:]
+		}
+		if rule[:3] == "max" && size > limit {
+			return "value must be at most " + rule[4:]
+		}
+	case strings.HasPrefix(rule, "oneof="):
+		actual := formatValue(value)
+		for _, allowed := range strings.Fields(rule[6:]) {
+			if actual == allowed {
+				return ""
+			}
+		}
+		return "value " + actual + " must be one of " + rule[6:]
+	case rule == "url":
+		parsed, err := url.Parse(formatValue(value))
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return "value " + formatValue(value) + " is not a valid url"
+		}
+	case rule == "hostname":
+		if host := formatValue(value); len(host) > 253 || !hostnamePattern.MatchString(host) {
+			return "value " + host + " is not a valid hostname"
+		}
+	case strings.HasPrefix(rule, "duration"):
+		return checkDuration(rule, value)
+	default:
+		return "unknown validation rule " + rule
+	}
+	return ""
+}
+
+func checkDuration(rule string, value reflect.Value) string {
+	if value.Type() != durationType {
+		return "rule " + rule + " can not be applied to " + value.Type().String()
+	}
+
+	condition := strings.TrimPrefix(rule, "duration")
+	operator := strings.TrimRight(condition, "0123456789.nsuµmh")
+	limit, err := time.ParseDuration(condition[len(operator):])
+	if err != nil {
+		return "invalid validation rule " + rule
+	}
+
+	actual := time.Duration(value.Int())
+	var ok bool
+	switch operator {
+	case ">=":
+		ok = actual >= limit
+	case ">":
+		ok = actual > limit
+	case "<=":
+		ok = actual <= limit
+	case "<":
+		ok = actual < limit
+	default:
+		return "invalid validation rule " + rule
+	}
+	if !ok {
+		return "duration " + actual.String() + " must be " + operator + " " + limit.String()
+	}
+	return ""
+}
+
+// measure returns the number to compare with min and max rules
+func measure(value reflect.Value) (float64, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(value.Len()), true
+	default:
+		return 0, false
+	}
+}
+
+func formatValue(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.String:
+		return value.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64)
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
+	default:
+		return value.Type().String()
+	}
+}
@@ -0,0 +1,238 @@
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testNestedObject struct {
+	Name    string  `validate:"required"`
+	Role    string  `validate:"oneof=Object Array"`
+	Integer int     `validate:"min=100,max=200"`
+	Float   float64 `conf:"float"`
+}
+
+type testNested struct {
+	Objects []testNestedObject `validate:"min=2"`
+	Vars    struct {
+		IntArray []int          `conf:"intArray"`
+		Small    map[string]int `conf:"small"`
+		Ignored  string         `conf:"-"`
+		App      *testNestedApp `conf:"app"`
+		Timeout  time.Duration  `conf:"timeout"`
+		Missing  *testNestedApp `conf:"missing"`
+	}
+}
+
+type testNestedApp struct {
+	Array    []string `validate:"max=4"`
+	Boolean2 bool
+	Boolean4 bool
+}
+
+type testInvalidEnv struct {
+	Server  string        `validate:"oneof=Gitlab Bitbucket"`
+	Port    int           `validate:"required,min=1,max=1024"`
+	Host    string        `validate:"hostname"`
+	Val     string        `validate:"url"`
+	Sample  time.Duration `validate:"duration>=1s"`
+	Missing string        `conf:"missing" validate:"required"`
+	Timeout struct {
+		Value string `validate:"required"`
+	} `conf:"timeout"`
+}
+
+func TestConfig_Unmarshal(t *testing.T) {
+	root, err := os.Executable()
+	if err != nil {
+		t.Error(err)
+	}
+
+	rootDir := filepath.Join(filepath.Dir(root), "..")
+	t.Log("Searching Config files at: " + rootDir)
+
+	configure, err := conf.New(filepath.Join(rootDir, "test_configs/valids"), rootDir, []conf.EvaluatorFunction{
+		new(testEvalFunction),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var nested testNested
+	if err = configure.Unmarshal("nested", &nested); err != nil {
+		t.Fatal(err)
+	}
+	if len(nested.Objects) != 2 || nested.Objects[1].Name != "Second" || nested.Objects[0].Float != 103.33 {
+		t.Errorf("Failed decoding objects: %+v", nested.Objects)
+	}
+	if len(nested.Vars.IntArray) != 7 || nested.Vars.Small["d"] != 2 {
+		t.Errorf("Failed decoding vars: %+v", nested.Vars)
+	}
+	if nested.Vars.App == nil || len(nested.Vars.App.Array) != 4 || !nested.Vars.App.Boolean2 || !nested.Vars.App.Boolean4 {
+		t.Errorf("Failed decoding app: %+v", nested.Vars.App)
+	}
+	if nested.Vars.Missing != nil {
+		t.Error("Missing pointer fields should stay nil")
+	}
+
+	var port int
+	if err = configure.Unmarshal("evaluators.env.port", &port); err != nil || port != 2020 {
+		t.Errorf("Failed decoding evaluated port: %d %v", port, err)
+	}
+
+	var env testInvalidEnv
+	err = configure.Unmarshal("evaluators.env", &env)
+	errs, ok := err.(conf.ValidationErrors)
+	if !ok {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+	t.Log(err)
+
+	expected := []string{
+		"evaluators.env.server",
+		"evaluators.env.port",
+		"evaluators.env.val",
+		"evaluators.env.sample",
+		"evaluators.env.missing",
+		"evaluators.env.timeout.value",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d violations, got %d", len(expected), len(errs))
+	}
+	for index, violation := range errs {
+		if violation.Key != expected[index] {
+			t.Errorf("Expected violation for %s, got %s", expected[index], violation)
+		}
+		if !strings.HasSuffix(violation.File, "evaluators.hjson") {
+			t.Errorf("Expected violation in evaluators.hjson, got %s", violation.File)
+		}
+	}
+
+	if err = configure.Unmarshal("nested", nested); err == nil {
+		t.Error("Expected an error for non pointer target")
+	}
+}
+
+type testLevel string
+
+type testEmbeddedTLS struct {
+	Cert string
+}
+
+type testEmbeddedLimits struct {
+	Rate int
+}
+
+type testEmbedded struct {
+	testLevel
+	*testEmbeddedTLS
+	testEmbeddedLimits
+	Port int
+}
+
+type TestEmbeddedTLS struct {
+	Cert string
+}
+
+type testEmbeddedPointer struct {
+	*TestEmbeddedTLS
+	Port int
+}
+
+func TestConfig_UnmarshalEmbedded(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_embedded")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "embedded.hjson"), `{
+		server: { testLevel: "debug", port: 8080, rate: 10, cert: "server.pem" }
+		plain: { port: 8081 }
+	}`)
+
+	configure, err := conf.New(configDir, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var server testEmbedded
+	if err = configure.Unmarshal("embedded.server", &server); err != nil {
+		t.Fatal(err)
+	}
+	if server.Port != 8080 || server.Rate != 10 || server.testLevel != "" || server.testEmbeddedTLS != nil {
+		t.Errorf("Expected unexported embedded fields which are not structs to be skipped, got %+v", server)
+	}
+
+	var pointer testEmbeddedPointer
+	if err = configure.Unmarshal("embedded.server", &pointer); err != nil {
+		t.Fatal(err)
+	}
+	if pointer.TestEmbeddedTLS == nil || pointer.Cert != "server.pem" {
+		t.Errorf("Expected embedded pointers to be inlined, got %+v", pointer)
+	}
+	var plain testEmbeddedPointer
+	if err = configure.Unmarshal("embedded.plain", &plain); err != nil {
+		t.Fatal(err)
+	}
+	if plain.TestEmbeddedTLS != nil || plain.Port != 8081 {
+		t.Errorf("Expected embedded pointers to stay nil without their keys, got %+v", plain)
+	}
+
+	defer conf.ResetDeclarations()
+	conf.Declare("embedded.server", conf.TypeOf(testEmbeddedPointer{}))
+	conf.Declare("embedded.plain", conf.TypeOf(testEmbedded{}))
+	expected := []string{"embedded.server.rate", "embedded.server.testLevel"}
+	if unknown := configure.UnknownKeys(); !reflect.DeepEqual(unknown, expected) {
+		t.Errorf("Expected unknown keys %v, got %v", expected, unknown)
+	}
+}
+
+type testDefaults struct {
+	Host    string
+	Port    int
+	Limits  testEmbeddedLimits
+	Account struct {
+		UserName string `validate:"required"`
+	}
+}
+
+func TestConfig_UnmarshalKeepsDefaults(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_defaults")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), `{
+		server: { port: 9090 }
+	}`)
+
+	configure, err := conf.New(configDir, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defaults := testDefaults{Host: "localhost", Port: 8080, Limits: testEmbeddedLimits{Rate: 5}}
+	server := defaults
+	err = configure.Unmarshal("app.server", &server)
+	if server.Host != "localhost" || server.Port != 9090 || server.Limits.Rate != 5 {
+		t.Errorf("Expected defaults of missing keys to be kept, got %+v", server)
+	}
+	errs, ok := err.(conf.ValidationErrors)
+	if !ok || len(errs) != 1 || errs[0].Key != "app.server.account.userName" {
+		t.Errorf("Expected the missing field under its config key, got %v", err)
+	}
+
+	missing := defaults
+	configure.Unmarshal("app.missing", &missing)
+	if missing.Host != "localhost" || missing.Port != 8080 || missing.Limits.Rate != 5 {
+		t.Errorf("Expected defaults of a missing key to be kept, got %+v", missing)
+	}
+}
@@ -0,0 +1,266 @@
+package conf
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// usageTracker records keys read through getters, handles and Unmarshal
+type usageTracker struct {
+	// read holds every key read, touched holds read keys and all of their parents
+	read    sync.Map
+	touched sync.Map
+}
+
+func (u *usageTracker) record(key string) {
+	if u == nil {
+		return
+	}
+	if _, ok := u.read.Load(key); ok {
+		return
+	}
+
+	u.read.Store(key, struct{}{})
+	for _, prefix := range keyPrefixes(key) {
+		u.touched.Store(prefix, struct{}{})
+	}
+}
+
+// used reports if key, one of its parents or one of its children was read
+func (u *usageTracker) used(key string) bool {
+	if _, ok := u.touched.Load(key); ok {
+		return true
+	}
+	for _, prefix := range keyPrefixes(key) {
+		if _, ok := u.read.Load(prefix); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// UnusedKeys returns all config keys that were never read with a getter,
+// a Handle or Unmarshal since the Config was created, sorted.
+// Arrays are reported as a single key
+func (c *Config) UnusedKeys() []string {
+	var unused []string
+	for _, key := range leafKeys(c.load().configs) {
+		if !c.usage.used(key) {
+			unused = append(unused, c.externalKey(key))
+		}
+	}
+	return unused
+}
+
+// UnknownKeys returns all config keys that are neither described by a schema in the
+// SchemaDir nor by a declaration registered with Declare, sorted.
+// A declaration without a type, or with a map or interface type, covers all keys below
+// it, a declaration with a struct type only covers the fields of the struct
+func (c *Config) UnknownKeys() []string {
+	unknown := c.unknownKeys(c.load())
+	for index, key := range unknown {
+		unknown[index] = c.externalKey(key)
+	}
+	return unknown
+}
+
+// unknownKeys returns the canonical keys of UnknownKeys
+func (c *Config) unknownKeys(s *snapshot) []string {
+	declared := Declarations()
+	for index := range declared {
+		declared[index].Key = c.canonicalKey(declared[index].Key)
+	}
+
+	var unknown []string
+	for _, key := range leafKeys(s.configs) {
+		if !s.schemaCovers(key) && !declarationsCover(declared, key) {
+			unknown = append(unknown, key)
+		}
+	}
+	return unknown
+}
+
+// CheckUnknownKeys is the strict mode check, it reports every key returned by
+// UnknownKeys as ValidationErrors
+func (c *Config) CheckUnknownKeys() error {
+	s := c.load()
+
+	var errs ValidationErrors
+	for _, key := range c.unknownKeys(s) {
+		errs = append(errs, &ValidationError{Key: key, Message: "unknown key"})
+	}
+	if len(errs) > 0 {
+		c.reportErrors(s, errs)
+		return errs
+	}
+	return nil
+}
+
+func (s *snapshot) schemaCovers(key string) bool {
+	parts := splitKeyPath(key)
+	schema := s.schemas[parts[0]]
+	if schema == nil {
+		return false
+	}
+	return schema.covers(schema.root, parts[1:])
+}
+
+// covers reports if the schema node describes the object path
+func (s *Schema) covers(node interface{}, path []string) bool {
+	if len(path) == 0 {
+		return true
+	}
+
+	schema, ok := node.(map[string]interface{})
+	if !ok {
+		// the true schema allows anything
+		allowed, _ := node.(bool)
+		return allowed
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		if target, err := s.resolveRef(ref); err == nil && s.covers(target, path) {
+			return true
+		}
+	}
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+		if subs, ok := schema[keyword].([]interface{}); ok {
+			for _, sub := range subs {
+				if s.covers(sub, path) {
+					return true
+				}
+			}
+		}
+	}
+
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		if property, ok := properties[path[0]]; ok {
+			return s.covers(property, path[1:])
+		}
+	}
+	if patternProperties, ok := schema["patternProperties"].(map[string]interface{}); ok {
+		for pattern, property := range patternProperties {
+			if s.patterns[pattern].MatchString(path[0]) {
+				return s.covers(property, path[1:])
+			}
+		}
+	}
+	if additional, ok := schema["additionalProperties"]; ok {
+		return s.covers(additional, path[1:])
+	}
+	return false
+}
+
+// declarationsCover reports if one of declared covers key, declared keys must be canonical
+func declarationsCover(declared []Declaration, key string) bool {
+	parts := splitKeyPath(key)
+	for _, d := range declared {
+		declaredParts := splitKeyPath(d.Key)
+		if len(declaredParts) > len(parts) || !equalParts(declaredParts, parts[:len(declaredParts)]) {
+			continue
+		}
+		if d.Type == nil || typeCovers(d.Type, parts[len(declaredParts):]) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeCovers reports if decoding into target would read the object path
+func typeCovers(target reflect.Type, path []string) bool {
+	if len(path) == 0 {
+		return true
+	}
+
+	switch target.Kind() {
+	case reflect.Ptr:
+		return typeCovers(target.Elem(), path)
+	case reflect.Map, reflect.Interface:
+		return true
+	case reflect.Struct:
+		for index := 0; index < target.NumField(); index++ {
+			field := target.Field(index)
+			name, skip := fieldKey(field)
+			if skip {
+				continue
+			}
+			if structType, ok := inlinedStruct(field); ok {
+				if typeCovers(structType, path) {
+					return true
+				}
+				continue
+			}
+			if field.PkgPath != "" {
+				continue
+			}
+			if strings.EqualFold(name, path[0]) {
+				return typeCovers(field.Type, path[1:])
+			}
+		}
+	}
+	return false
+}
+
+// leafKeys returns the dotted keys of all values inside configs which are not objects, sorted
+func leafKeys(configs map[string]interface{}) []string {
+	var keys []string
+	var walk func(prefix string, value interface{})
+	walk = func(prefix string, value interface{}) {
+		object, ok := value.(map[string]interface{})
+		if !ok || (len(object) == 0 && prefix != "") {
+			keys = append(keys, prefix)
+			return
+		}
+		for name, item := range object {
+			walk(joinKey(prefix, name), item)
+		}
+	}
+	walk("", configs)
+
+	sort.Strings(keys)
+	return keys
+}
+
+// keyPrefixes returns key and all of its parents, for a.b[0].c those are
+// a, a.b, a.b[0] and a.b[0].c
+func keyPrefixes(key string) []string {
+	steps, err := parsePath(key, ".")
+	if err != nil {
+		return []string{key}
+	}
+
+	prefixes := make([]string, len(steps))
+	for index := range steps {
+		prefixes[index] = formatPath(steps[:index+1], ".")
+	}
+	return prefixes
+}
+
+// splitKeyPath splits a dotted key into the names of its objects,
+// array indexes are dropped
+func splitKeyPath(key string) []string {
+	steps, _ := parsePath(key, ".")
+	var parts []string
+	for _, step := range steps {
+		if step.kind == stepName {
+			parts = append(parts, step.name)
+		}
+	}
+	return parts
+}
+
+func equalParts(a []string, b []string) bool {
+	for index := range a {
+		if a[index] != b[index] {
+			return false
+		}
+	}
+	return true
+}
+
+func indexKey(key string, index int) string {
+	return key + "[" + strconv.Itoa(index) + "]"
+}
@@ -0,0 +1,139 @@
+package conf_test
+
+import (
+	"github.com/peyman-abdi/conf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type testUsageServer struct {
+	Host string `conf:"host"`
+	Port int    `conf:"port"`
+}
+
+func TestConfig_UnusedKeys(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_usage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "usage.hjson"), `{
+		server: { host: "localhost", port: 8080, tls: false }
+		databse: { host: "db" }
+		hosts: ["a", "b"]
+		logger: { level: "debug", output: "stdout" }
+	}`)
+
+	configure, err := conf.New(configDir, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var server testUsageServer
+	if err = configure.Unmarshal("usage.server", &server); err != nil {
+		t.Fatal(err)
+	}
+	configure.GetString("usage.hosts[1]", "")
+	configure.GetMap("usage.logger", nil)
+
+	expected := []string{"usage.databse.host", "usage.server.tls"}
+	if unused := configure.UnusedKeys(); !reflect.DeepEqual(unused, expected) {
+		t.Errorf("Expected unused keys %v, got %v", expected, unused)
+	}
+}
+
+func TestConfig_UnknownKeys(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "unknown.hjson"), `{
+		server: { host: "localhost", port: 8080, tls: false }
+		databse: { host: "db" }
+		logger: { level: "debug" }
+		plugins: { any: { thing: 1 } }
+	}`)
+	if err = os.Mkdir(filepath.Join(configDir, conf.SchemaDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeConfigFile(t, filepath.Join(configDir, conf.SchemaDir, "unknown.schema.json"), `{
+		"properties": {
+			"logger": { "properties": { "level": { "type": "string" } } },
+			"plugins": { "additionalProperties": true }
+		}
+	}`)
+
+	configure, err := conf.New(configDir, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conf.ResetDeclarations()
+	conf.Declare("unknown.server", conf.TypeOf(testUsageServer{}))
+
+	expected := []string{"unknown.databse.host", "unknown.server.tls"}
+	if unknown := configure.UnknownKeys(); !reflect.DeepEqual(unknown, expected) {
+		t.Errorf("Expected unknown keys %v, got %v", expected, unknown)
+	}
+
+	err = configure.CheckUnknownKeys()
+	if errs, ok := err.(conf.ValidationErrors); !ok || len(errs) != 2 || errs[0].File == "" {
+		t.Errorf("Expected two unknown key errors, got %v", err)
+	}
+}
+
+func TestConfig_UsageKeyDelimiter(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_delimiter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "slashed.hjson"), `{
+		server: { host: "localhost", port: "http", tls: false }
+		logger: { level: "debug" }
+	}`)
+
+	configure, err := conf.Load(conf.WithConfigDirs(configDir), conf.WithKeyDelimiter("/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conf.ResetDeclarations()
+	conf.Declare("slashed/server", conf.TypeOf(testUsageServer{}))
+	conf.Declare("slashed/logger/level", conf.Required)
+	conf.Declare("slashed/logger/output", conf.Required)
+
+	configure.GetString("slashed/logger/level", "")
+	expected := []string{"slashed/server/host", "slashed/server/port", "slashed/server/tls"}
+	if unused := configure.UnusedKeys(); !reflect.DeepEqual(unused, expected) {
+		t.Errorf("Expected unused keys %v, got %v", expected, unused)
+	}
+
+	expected = []string{"slashed/server/tls"}
+	if unknown := configure.UnknownKeys(); !reflect.DeepEqual(unknown, expected) {
+		t.Errorf("Expected unknown keys %v, got %v", expected, unknown)
+	}
+	err = configure.CheckUnknownKeys()
+	if errs, ok := err.(conf.ValidationErrors); !ok || len(errs) != 1 || errs[0].Key != expected[0] || errs[0].File == "" {
+		t.Errorf("Expected an unknown key error for %s, got %v", expected[0], err)
+	}
+
+	err = configure.Check()
+	errs, ok := err.(conf.ValidationErrors)
+	if !ok || len(errs) != 2 || errs[0].Key != "slashed/logger/output" || errs[1].Key != "slashed/server/port" || errs[1].File == "" {
+		t.Errorf("Expected missing and invalid keys with the key delimiter, got %v", err)
+	}
+
+	err = configure.Require("slashed/logger/level", "slashed/logger/output")
+	errs, ok = err.(conf.ValidationErrors)
+	if !ok || len(errs) != 1 || errs[0].Key != "slashed/logger/output" || errs[0].File == "" {
+		t.Errorf("Expected the missing key with the key delimiter and its file, got %v", err)
+	}
+}
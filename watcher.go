@@ -0,0 +1,430 @@
+package conf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChangeCallback is called with the old and the new resolved values of a
+// watched key prefix whenever a reload changes any value under that prefix.
+// Values are nil when the key did not exist before or does not exist anymore
+type ChangeCallback func(old interface{}, new interface{})
+
+type changeCallback struct {
+	id       uint64
+	prefix   string
+	callback ChangeCallback
+}
+
+// notifier is implemented by the platform dependant file system notification
+// backends, see watcher_linux.go
+type notifier interface {
+	// Wait blocks at most timeout and reports if any relevant file has changed
+	Wait(timeout time.Duration) (bool, error)
+	// Add starts watching new directories, already watched ones are ignored.
+	// It reports if any directory was added
+	Add(dirs []string) (bool, error)
+	Close() error
+}
+
+var (
+	errNotifyNotSupported = errors.New("conf: file system notifications are not supported on this platform")
+	errFrozen             = errors.New("conf: snapshots can not be reloaded")
+)
+
+type watcher struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// OnChange registers callback to be called after a reload changed the resolved value
+// of keyPrefix, or of any key under it. keyPrefix can be a single value like
+// "app.server.port" or an object like "app.server"; an empty keyPrefix watches everything.
+// Callbacks can write to the config with Set or Reload, the changes they make are
+// notified after they return
+func (c *Config) OnChange(keyPrefix string, callback ChangeCallback) {
+	c.addChangeCallback(keyPrefix, callback)
+}
+
+func (c *Config) addChangeCallback(keyPrefix string, callback ChangeCallback) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastCallbackID++
+	c.callbacks = append(c.callbacks, changeCallback{id: c.lastCallbackID, prefix: c.canonicalKey(keyPrefix), callback: callback})
+	return c.lastCallbackID
+}
+
+func (c *Config) removeChangeCallback(id uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for index, cb := range c.callbacks {
+		if cb.id == id {
+			c.callbacks = append(c.callbacks[:index:index], c.callbacks[index+1:]...)
+			return
+		}
+	}
+}
+
+// OnReloadError registers a function called with errors happened during reloads
+// triggered by the watcher. The config keeps its previous values on those errors
+func (c *Config) OnReloadError(callback func(err error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.reloadErrorCallback = callback
+}
+
+// Reload parses all config files again and swaps them with the current ones.
+// If any of the files fails to parse or validate against its schema the current configs are kept and the error is returned.
+// After a successful reload callbacks registered with OnChange are called for changed keys
+func (c *Config) Reload() error {
+	if c.frozen {
+		return errFrozen
+	}
+
+	// deferred first so callbacks run after writeMu is released
+	defer c.dispatchChanges()
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_, err := c.reload()
+	return err
+}
+
+// reload loads the configs again and stores them, callers must hold writeMu.
+// loaded is false if loading failed and the current snapshot was kept
+func (c *Config) reload() (loaded bool, err error) {
+	old := c.load()
+	next, err := c.loadSnapshot(old.evaluators)
+	if next == nil {
+		return false, err
+	}
+
+	c.swap(old, next)
+
+	return true, err
+}
+
+// swap stores next as the current snapshot and queues the notification of change callbacks,
+// callers must hold writeMu and call dispatchChanges after releasing it
+func (c *Config) swap(old *snapshot, next *snapshot) {
+	c.store(next)
+
+	c.notifyMu.Lock()
+	c.pendingChanges = append(c.pendingChanges, pendingChange{old: old, next: next})
+	c.notifyMu.Unlock()
+}
+
+// pendingChange is a stored snapshot whose change callbacks have not been called yet
+type pendingChange struct {
+	old  *snapshot
+	next *snapshot
+}
+
+// dispatchChanges calls change callbacks of queued swaps in order. Callbacks may write to
+// the config; changes they make are dispatched by the running call once they return
+func (c *Config) dispatchChanges() {
+	c.notifyMu.Lock()
+	if c.dispatching {
+		c.notifyMu.Unlock()
+		return
+	}
+	c.dispatching = true
+	for len(c.pendingChanges) > 0 {
+		change := c.pendingChanges[0]
+		c.pendingChanges = c.pendingChanges[1:]
+		c.notifyMu.Unlock()
+
+		c.mu.RLock()
+		callbacks := make([]changeCallback, len(c.callbacks))
+		copy(callbacks, c.callbacks)
+		c.mu.RUnlock()
+
+		notifyChanges(change.old, change.next, callbacks)
+
+		c.notifyMu.Lock()
+	}
+	c.dispatching = false
+	c.notifyMu.Unlock()
+}
+
+// StartWatcher watches the config and env directories and reloads the configs
+// when a config file or an env file of the active profile changes. Files are polled every interval, unless notify is true
+// and the platform supports file system notifications (inotify on linux),
+// in which case interval is only used as fallback when notifications are not available
+func (c *Config) StartWatcher(interval time.Duration, notify bool) error {
+	if interval <= 0 {
+		return errors.New("conf: watcher interval must be positive")
+	}
+	if c.frozen {
+		return errFrozen
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.watcher != nil {
+		return errors.New("conf: watcher is already running")
+	}
+
+	var n notifier
+	if notify {
+		var err error
+		n, err = newNotifier(c.watchedDirs(), c.watchedFile)
+		if err != nil && err != errNotifyNotSupported {
+			return err
+		}
+	}
+
+	w := &watcher{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	c.watcher = w
+
+	if n != nil {
+		go c.notifyLoop(w, n, interval)
+	} else {
+		go c.pollLoop(w, interval)
+	}
+
+	return nil
+}
+
+// StopWatcher stops the watcher started with StartWatcher and waits for it to exit
+func (c *Config) StopWatcher() {
+	c.mu.Lock()
+	w := c.watcher
+	c.watcher = nil
+	c.mu.Unlock()
+
+	if w != nil {
+		close(w.stop)
+		<-w.done
+	}
+}
+
+func (c *Config) pollLoop(w *watcher, interval time.Duration) {
+	defer close(w.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	loaded := c.fingerprint()
+	previous := loaded
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			// only reload once files stopped changing for a whole interval,
+			// so half written files are not picked up
+			current := c.fingerprint()
+			if current != loaded && current == previous {
+				loaded = current
+				c.reloadFromWatcher()
+			}
+			previous = current
+		}
+	}
+}
+
+func (c *Config) notifyLoop(w *watcher, n notifier, interval time.Duration) {
+	defer close(w.done)
+	defer n.Close()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		changed, err := n.Wait(interval)
+		if err != nil {
+			c.reportReloadError(err)
+			continue
+		}
+		if !changed {
+			// directories created while they were not watched, like a removed and
+			// created again config directory, send no events
+			added, err := n.Add(c.watchedDirs())
+			if err != nil {
+				c.reportReloadError(err)
+			}
+			if !added {
+				continue
+			}
+		}
+
+		// editors usually write a file in several steps, wait for them to settle
+		// but not longer than maxSettleDelay, so files written constantly still reload
+		settled := time.Now().Add(maxSettleDelay)
+		for changed && time.Now().Before(settled) {
+			select {
+			case <-w.stop:
+				return
+			default:
+			}
+			changed, _ = n.Wait(50 * time.Millisecond)
+		}
+
+		c.reloadFromWatcher()
+		if _, err = n.Add(c.watchedDirs()); err != nil {
+			c.reportReloadError(err)
+		}
+	}
+}
+
+// maxSettleDelay is the longest time the notify watcher waits for files to stop changing
+const maxSettleDelay = time.Second
+
+func (c *Config) reloadFromWatcher() {
+	if err := c.Reload(); err != nil {
+		c.reportReloadError(err)
+	}
+}
+
+func (c *Config) reportReloadError(err error) {
+	c.mu.RLock()
+	callback := c.reloadErrorCallback
+	c.mu.RUnlock()
+
+	if callback != nil {
+		callback(err)
+	}
+}
+
+// watchedDirs returns the config directories and all of their sub directories
+// plus the env directories
+func (c *Config) watchedDirs() []string {
+	var dirs []string
+	for _, configDir := range c.configDirs {
+		filepath.Walk(configDir, func(path string, info os.FileInfo, err error) error {
+			if info != nil && info.IsDir() {
+				dirs = append(dirs, path)
+			}
+			return nil
+		})
+	}
+	return append(dirs, c.envDirs...)
+}
+
+// watchedFile reports if a change of path can change the configs, which are the files
+// inside the config directories and the env files of the active profile
+func (c *Config) watchedFile(path string) bool {
+	path = filepath.Clean(path)
+	for _, configDir := range c.configDirs {
+		configDir = filepath.Clean(configDir)
+		if path == configDir || strings.HasPrefix(path, configDir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	for _, envDir := range c.envDirs {
+		for _, name := range envFiles(c.load().profile) {
+			if path == filepath.Join(envDir, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fingerprint summarizes names, sizes and modification times of all
+// watched files so polling can detect changes
+func (c *Config) fingerprint() string {
+	var builder strings.Builder
+	add := func(path string, info os.FileInfo) {
+		builder.WriteString(path)
+		builder.WriteByte('|')
+		builder.WriteString(strconv.FormatInt(info.Size(), 10))
+		builder.WriteByte('|')
+		builder.WriteString(strconv.FormatInt(info.ModTime().UnixNano(), 10))
+		builder.WriteByte('\n')
+	}
+
+	for _, configDir := range c.configDirs {
+		filepath.Walk(configDir, func(path string, info os.FileInfo, err error) error {
+			if info != nil && !info.IsDir() {
+				add(path, info)
+			}
+			return nil
+		})
+	}
+	for _, envDir := range c.envDirs {
+		for _, name := range envFiles(c.load().profile) {
+			path := filepath.Join(envDir, name)
+			if info, err := os.Stat(path); err == nil {
+				add(path, info)
+			}
+		}
+	}
+
+	return builder.String()
+}
+
+func notifyChanges(old *snapshot, next *snapshot, callbacks []changeCallback) {
+	for _, cb := range callbacks {
+		oldValue := resolvePrefix(old, cb.prefix)
+		newValue := resolvePrefix(next, cb.prefix)
+		if !reflect.DeepEqual(oldValue, newValue) {
+			cb.callback(oldValue, newValue)
+		}
+	}
+}
+
+func resolvePrefix(s *snapshot, prefix string) interface{} {
+	if prefix == "" {
+		return resolveValue(s, "", s.configs)
+	}
+	return get(s, prefix, nil)
+}
+
+// resolveValue returns a copy of value, the value of the canonical key, with all evaluator
+// calls inside nested maps and arrays evaluated except inside the literals of s
+func resolveValue(s *snapshot, key string, value interface{}) interface{} {
+	return resolveLiteral(s, key, value, s.literals[key])
+}
+
+// resolveLiteral copies value without evaluating the strings inside it when literal is set
+func resolveLiteral(s *snapshot, key string, value interface{}, literal bool) interface{} {
+	// keys of children are only needed to find literals inside value
+	childKeys := len(s.literals) > 0 && !literal
+
+	switch typed := value.(type) {
+	case string:
+		if literal {
+			return typed
+		}
+		return evalStringValue(s, typed, nil)
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(typed))
+		for name, item := range typed {
+			var itemKey string
+			if childKeys {
+				itemKey = joinKey(key, name)
+			}
+			resolved[name] = resolveLiteral(s, itemKey, item, literal || s.literals[itemKey])
+		}
+		return resolved
+	case []interface{}:
+		resolved := make([]interface{}, len(typed))
+		for index, item := range typed {
+			var itemKey string
+			if childKeys {
+				itemKey = indexKey(key, index)
+			}
+			resolved[index] = resolveLiteral(s, itemKey, item, literal || s.literals[itemKey])
+		}
+		return resolved
+	default:
+		return value
+	}
+}
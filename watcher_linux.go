@@ -0,0 +1,146 @@
+//go:build linux
+// +build linux
+
+package conf
+
+import (
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF
+
+type inotifyNotifier struct {
+	fd      int
+	epollFd int
+	// watched maps watched directories to their watch descriptors, paths maps them back
+	watched  map[string]int32
+	paths    map[int32]string
+	relevant func(path string) bool
+}
+
+var _ notifier = (*inotifyNotifier)(nil)
+
+func newNotifier(dirs []string, relevant func(path string) bool) (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	epollFd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	event := syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(fd)}
+	if err = syscall.EpollCtl(epollFd, syscall.EPOLL_CTL_ADD, fd, &event); err != nil {
+		syscall.Close(epollFd)
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	n := &inotifyNotifier{
+		fd:       fd,
+		epollFd:  epollFd,
+		watched:  make(map[string]int32),
+		paths:    make(map[int32]string),
+		relevant: relevant,
+	}
+	if _, err = n.Add(dirs); err != nil {
+		n.Close()
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func (n *inotifyNotifier) Add(dirs []string) (bool, error) {
+	added := false
+	for _, dir := range dirs {
+		if _, ok := n.watched[dir]; ok {
+			continue
+		}
+		wd, err := syscall.InotifyAddWatch(n.fd, dir, inotifyMask)
+		if err != nil {
+			return added, err
+		}
+		n.watched[dir] = int32(wd)
+		n.paths[int32(wd)] = dir
+		added = true
+	}
+	return added, nil
+}
+
+func (n *inotifyNotifier) Wait(timeout time.Duration) (bool, error) {
+	deadline := time.Now().Add(timeout)
+	events := make([]syscall.EpollEvent, 1)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return false, nil
+		}
+		count, err := syscall.EpollWait(n.epollFd, events, int(remaining/time.Millisecond))
+		if err == syscall.EINTR {
+			return false, nil
+		}
+		if err != nil || count == 0 {
+			return false, err
+		}
+
+		// drain all pending events, a single reload covers them all
+		if n.readEvents() {
+			return true, nil
+		}
+	}
+}
+
+// readEvents reads all pending events, dropping the watches the kernel removed, and
+// reports if any of them is relevant
+func (n *inotifyNotifier) readEvents() bool {
+	changed := false
+	buffer := make([]byte, 4096)
+	for {
+		read, err := syscall.Read(n.fd, buffer)
+		if err != nil || read <= 0 {
+			return changed
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= read; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			start := offset + syscall.SizeofInotifyEvent
+			offset = start + int(event.Len)
+
+			if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				changed = true
+				continue
+			}
+			dir, ok := n.paths[event.Wd]
+			if !ok {
+				continue
+			}
+			if event.Mask&syscall.IN_IGNORED != 0 {
+				// the directory was removed, watch it again once it is created again
+				delete(n.watched, dir)
+				delete(n.paths, event.Wd)
+			}
+
+			path := dir
+			if event.Len > 0 {
+				path = filepath.Join(dir, strings.TrimRight(string(buffer[start:offset]), "\x00"))
+			}
+			if n.relevant(path) {
+				changed = true
+			}
+		}
+	}
+}
+
+func (n *inotifyNotifier) Close() error {
+	syscall.Close(n.epollFd)
+	return syscall.Close(n.fd)
+}
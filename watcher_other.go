@@ -0,0 +1,8 @@
+//go:build !linux
+// +build !linux
+
+package conf
+
+func newNotifier(dirs []string, relevant func(path string) bool) (notifier, error) {
+	return nil, errNotifyNotSupported
+}
@@ -0,0 +1,284 @@
+package conf_test
+
+import (
+	"fmt"
+	"github.com/peyman-abdi/conf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConfig_Reload(t *testing.T) {
+	for _, notify := range []bool{false, true} {
+		configDir, err := ioutil.TempDir("", "conf_watch")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(configDir)
+
+		appFile := filepath.Join(configDir, "app.hjson")
+		writeConfigFile(t, appFile, "{ server: { port: 8080, host: \"localhost\" } }")
+
+		configure, err := conf.New(configDir, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		changes := make(chan [2]interface{}, 10)
+		configure.OnChange("app.server.port", func(old interface{}, new interface{}) {
+			changes <- [2]interface{}{old, new}
+		})
+		configure.OnChange("app.server.host", func(old interface{}, new interface{}) {
+			t.Errorf("Change callback called for unchanged key, old: %v new: %v", old, new)
+		})
+		errors := make(chan error, 10)
+		configure.OnReloadError(func(err error) {
+			errors <- err
+		})
+
+		if err = configure.StartWatcher(10*time.Millisecond, notify); err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		writeConfigFile(t, appFile, "{ server: { port: 9090, host: \"localhost\" } }")
+
+		select {
+		case change := <-changes:
+			if change[0] != 8080.0 || change[1] != 9090.0 {
+				t.Errorf("Unexpected change values: %v", change)
+			}
+		case <-time.After(2 * time.Second):
+			t.Errorf("Change callback was not called (notify: %v)", notify)
+		}
+
+		if port := configure.GetInt("app.server.port", 0); port != 9090 {
+			t.Errorf("Expected reloaded port 9090, found %d", port)
+		}
+
+		writeConfigFile(t, appFile, "{ this file is not valid")
+		select {
+		case <-errors:
+		case <-time.After(2 * time.Second):
+			t.Errorf("Reload error callback was not called (notify: %v)", notify)
+		}
+
+		if port := configure.GetInt("app.server.port", 0); port != 9090 {
+			t.Errorf("Expected to keep port 9090 after a failed reload, found %d", port)
+		}
+
+		configure.StopWatcher()
+	}
+}
+
+func TestConfig_ConcurrentReload(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_race")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	appFile := filepath.Join(configDir, "app.hjson")
+	writeConfigFile(t, appFile, "{ server: { port: 1, hosts: [\"a\", \"b\"] } }")
+
+	configure, err := conf.New(configDir, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pinned := configure.Snapshot()
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				configure.GetInt("app.server.port", 0)
+				configure.GetStringArray("app.server.hosts", nil)
+				configure.GetMap("app.server", nil)
+				configure.IsSet("app.server.port")
+				if port := pinned.GetInt("app.server.port", 0); port != 1 {
+					t.Errorf("Snapshot changed during reload, port is %d", port)
+					return
+				}
+			}
+		}()
+	}
+
+	for port := 2; port < 50; port++ {
+		writeConfigFile(t, appFile, fmt.Sprintf("{ server: { port: %d, hosts: [\"a\", \"b\"] } }", port))
+		if err = configure.Reload(); err != nil {
+			t.Error(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if port := configure.GetInt("app.server.port", 0); port != 49 {
+		t.Errorf("Expected port 49 after reloads, found %d", port)
+	}
+	if err = pinned.Reload(); err == nil {
+		t.Error("Expected an error reloading a snapshot")
+	}
+}
+
+func TestConfig_OnChangeWritesBack(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_write_back")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), "{ a: 1, b: 1 }")
+
+	configure, err := conf.New(configDir, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var changes []string
+	configure.OnChange("app.a", func(old interface{}, new interface{}) {
+		changes = append(changes, fmt.Sprintf("a=%v", new))
+		if err := configure.Set("app.b", new); err != nil {
+			t.Error(err)
+		}
+		if err := configure.Reload(); err != nil {
+			t.Error(err)
+		}
+	})
+	configure.OnChange("app.b", func(old interface{}, new interface{}) {
+		changes = append(changes, fmt.Sprintf("b=%v", new))
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := configure.Set("app.a", 2); err != nil {
+			t.Error(err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Set deadlocked writing back from a change callback")
+	}
+
+	if b := configure.GetInt("app.b", 0); b != 2 {
+		t.Errorf("Expected the callback to set app.b to 2, got %d", b)
+	}
+	if fmt.Sprint(changes) != "[a=2 b=2]" {
+		t.Errorf("Expected changes in order, got %v", changes)
+	}
+}
+
+// writeConfigFile replaces the file at path atomically like deployment tools do
+func TestConfig_WatchRecreatedDir(t *testing.T) {
+	for _, notify := range []bool{false, true} {
+		rootDir, err := ioutil.TempDir("", "conf_watch_recreated")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(rootDir)
+
+		configDir := filepath.Join(rootDir, "configs")
+		if err = os.Mkdir(configDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		writeConfigFile(t, filepath.Join(configDir, "app.hjson"), "{ a: 1 }")
+
+		configure, err := conf.New(configDir, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = configure.StartWatcher(10*time.Millisecond, notify); err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		if err = os.RemoveAll(configDir); err != nil {
+			t.Fatal(err)
+		}
+		// let the watcher handle the removal before the directory is created again
+		time.Sleep(200 * time.Millisecond)
+		if err = os.Mkdir(configDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		writeConfigFile(t, filepath.Join(configDir, "app.hjson"), "{ a: 3 }")
+
+		deadline := time.Now().Add(2 * time.Second)
+		for configure.GetInt("app.a", 0) != 3 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if a := configure.GetInt("app.a", 0); a != 3 {
+			t.Errorf("Expected the configs of the recreated directory, got %d (notify: %v)", a, notify)
+		}
+		configure.StopWatcher()
+	}
+}
+
+func TestConfig_StopWatcherWhileFilesChange(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "conf_watch_busy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+	envDir, err := ioutil.TempDir("", "conf_watch_busy_env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(envDir)
+
+	writeConfigFile(t, filepath.Join(configDir, "app.hjson"), "{ a: 1 }")
+	configure, err := conf.New(configDir, envDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = configure.StartWatcher(10*time.Millisecond, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// an application log inside the env directory is not an env file
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			case <-time.After(5 * time.Millisecond):
+				ioutil.WriteFile(filepath.Join(envDir, "app.log"), []byte(fmt.Sprint(i)), 0644)
+			}
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		configure.StopWatcher()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected StopWatcher to return while files change")
+	}
+}
+
+func writeConfigFile(t testing.TB, path string, content string) {
+	if err := ioutil.WriteFile(path+".tmp", []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		t.Fatal(err)
+	}
+}